@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Accounts maps cards and categories to the accounts of a double-entry
+// journal. Every field is optional.
+type Accounts struct {
+	Cards           map[string]string `json:"cards"`           // Liability account per card number, or its last digits
+	DefaultCard     string            `json:"defaultCard"`     // Liability account of cards not in Cards
+	Categories      map[string]string `json:"categories"`      // Expense account per category; subcategories extend their parent's
+	ForeignFees     string            `json:"foreignFees"`     // Expense account of foreign transaction fees
+	Payments        string            `json:"payments"`        // Account that pays the card bill
+	OpeningBalances string            `json:"openingBalances"` // Equity account balancing the first statement's previous balance
+}
+
+// defaultAccounts are used for any field the accounts file leaves empty
+var defaultAccounts = Accounts{
+	DefaultCard:     "Liabilities:CreditCard",
+	ForeignFees:     "Expenses:Bank:ForeignFees",
+	Payments:        "Assets:Bank",
+	OpeningBalances: "Equity:Opening-Balances",
+}
+
+// LoadAccounts reads the explicit accounts file, or accounts.json in the
+// configuration directory when it exists
+func LoadAccounts(explicit string) (Accounts, error) {
+	accounts := defaultAccounts
+	path := explicit
+	if path == "" {
+		path = configPath("accounts.json")
+		if path == "" {
+			return accounts, nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return accounts, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return accounts, err
+	}
+	var file Accounts
+	if err := json.Unmarshal(data, &file); err != nil {
+		return accounts, fmt.Errorf("%s: %w", path, err)
+	}
+
+	accounts.Cards = file.Cards
+	accounts.Categories = make(map[string]string, len(file.Categories))
+	for cat, account := range file.Categories {
+		accounts.Categories[NormalizeCategory(cat)] = account
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&accounts.DefaultCard, &file.DefaultCard},
+		{&accounts.ForeignFees, &file.ForeignFees},
+		{&accounts.Payments, &file.Payments},
+		{&accounts.OpeningBalances, &file.OpeningBalances},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return accounts, nil
+}
+
+// Card returns the liability account of a card. Keys match the whole card
+// number or its last digits; the longest match wins.
+func (a Accounts) Card(cardNo string) string {
+	account, best := a.DefaultCard, 0
+	for key, acc := range a.Cards {
+		if len(key) > best && strings.HasSuffix(cardNo, key) {
+			account, best = acc, len(key)
+		}
+	}
+	return account
+}
+
+// Category returns the expense account of a category: its own mapping, or
+// the nearest mapped ancestor's followed by the remaining path, or
+// Expenses followed by the whole path
+func (a Accounts) Category(cat string) string {
+	account, rest := "Expenses", strings.Split(cat, categorySeparator)
+	for i := len(rest); i > 0; i-- {
+		if acc, ok := a.Categories[strings.Join(rest[:i], categorySeparator)]; ok {
+			account, rest = acc, rest[i:]
+			break
+		}
+	}
+	for _, part := range rest {
+		account += ":" + accountComponent(part)
+	}
+	return account
+}
+
+// accountComponent turns a category name into an account name component:
+// words are joined with dashes and an ASCII first letter is capitalized
+func accountComponent(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = true
+			continue
+		}
+		if dash && b.Len() > 0 {
+			b.WriteRune('-')
+		}
+		dash = false
+		b.WriteRune(r)
+	}
+
+	s := b.String()
+	if s == "" {
+		return "Other"
+	}
+	if c := s[0]; c >= 'a' && c <= 'z' {
+		s = string(c-'a'+'A') + s[1:]
+	}
+	return s
+}
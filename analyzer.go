@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"os"
 	"regexp"
 	"strings"
@@ -37,105 +36,69 @@ func ToCDB(str string) string {
 	return result.String()
 }
 
-// GetCleanDescription removes payment provider prefixes from description
-func GetCleanDescription(normalizedDesc string) string {
-	// Remove APE or APExxxx (4 digits) prefix
-	applePayRegex := regexp.MustCompile(`^APE\d{0,4}`)
-	cleanDesc := applePayRegex.ReplaceAllString(normalizedDesc, "")
-
-	// Remove LINE Pay prefix (連加*)
-	if strings.HasPrefix(cleanDesc, "連加*") {
-		cleanDesc = strings.TrimPrefix(cleanDesc, "連加*")
-	}
-
-	// Remove Jkopay prefix (街口電支-)
-	if strings.HasPrefix(cleanDesc, "街口電支-") {
-		cleanDesc = strings.TrimPrefix(cleanDesc, "街口電支-")
-	}
+// applePayPrefix matches the APE or APExxxx (card last 4 digits) prefix
+var applePayPrefix = regexp.MustCompile(`^APE\d{0,4}`)
 
-	return cleanDesc
+// CleanStep is the description after one step of GetCleanDescription
+type CleanStep struct {
+	Name   string
+	Result string
 }
 
-// DetectDetailedCategory detects granular category based on transaction description
-func DetectDetailedCategory(normalizedDesc string) string {
-	desc := strings.ToUpper(normalizedDesc)
-
-	// Transportation
-	transportPrefixes := []string{"LIME", "UBER", "UBR*", "FREENOW", "ZITY", "FLIXBUS", "FNM*", "FNM ", "TRENITALIA", "TRENORD", "TRAIN", "SCOOTER", "RAILWAY"}
-	for _, prefix := range transportPrefixes {
-		if strings.HasPrefix(desc, prefix) {
-			return CategoryTransport
+// CleanDescriptionSteps applies the cleaning steps of GetCleanDescription
+// in order. The first entry is the input; a step is only recorded when it
+// changed the description.
+func CleanDescriptionSteps(normalizedDesc string) []CleanStep {
+	steps := []CleanStep{{Name: "normalized", Result: normalizedDesc}}
+	apply := func(name, result string) {
+		if result != steps[len(steps)-1].Result {
+			steps = append(steps, CleanStep{Name: name, Result: result})
 		}
 	}
 
-	// Food & Groceries
-	foodPrefixes := []string{
-		"CIBO", "PANIFICIO", "DELIVEROO", "CAFE", "CAFFE", "MACELLERIA",
-		"ESSELUNGA", "MERCATO", "MERCADO", "RISTORANTE", "RESTAURANT", "OSTERIA", "GELATERIA", "GELATO", "GELATI", "PIZZA", "PIZZERIA",
-		"BURGER", "CONAD", "CARREFOUR", "EATALY", "BAR", "TRATTORIA", "DM-",
-		"GLOVO", "KFC", "MCDONALDS", "NESPRESSO", "PASTICCERIA",
-		"PRETAMANGER", "FIVEGUYS", "AUTOGRILL", "STARBUCKS", "DRINK",
-	}
-	for _, prefix := range foodPrefixes {
-		if strings.Contains(desc, prefix) {
-			return CategoryFood
-		}
-	}
+	// Remove APE or APExxxx (4 digits) prefix
+	cleanDesc := applePayPrefix.ReplaceAllString(normalizedDesc, "")
+	apply("Apple Pay prefix", cleanDesc)
 
-	// Shopping
-	shoppingPrefixes := []string{"AMAZON*", "WWW.AMAZON", "DECATHLON", "BRICOCENTER", "TIGROS", "TEMU.COM", "UNIQLO"}
-	for _, prefix := range shoppingPrefixes {
-		if strings.Contains(desc, prefix) {
-			return CategoryShopping
-		}
-	}
+	// Remove LINE Pay prefix (連加*)
+	cleanDesc = strings.TrimPrefix(cleanDesc, "連加*")
+	apply("LINE Pay prefix", cleanDesc)
 
-	// Travel & Accommodation
-	travelPrefixes := []string{"AIRBNB", "ALBERGO", "AIRPORT", "EASYJET", "TRIP.COM", "EVAAIR", "RYANAIR", "FLYSCOOT", "GOTOGATE", "BOOKINGCOM", "HOTEL", "KKDAY", "KIWICOM"}
-	for _, prefix := range travelPrefixes {
-		if strings.Contains(desc, prefix) {
-			return CategoryTravel
-		}
-	}
+	// Remove Jkopay prefix (街口電支-)
+	cleanDesc = strings.TrimPrefix(cleanDesc, "街口電支-")
+	apply("Jkopay prefix", cleanDesc)
 
-	// Utilities & Services
-	utilitiesPrefixes := []string{"APPLE.COM", "VODAFONE", "AWS", "AMAZONWEBSERVICES", "1PASSWORD", "POLITECNICO", "POSTEITALIA", "OPENAI", "POLISPORTIVA", "PORKBUN"}
-	for _, prefix := range utilitiesPrefixes {
-		if strings.Contains(desc, prefix) {
-			return CategoryUtilities
-		}
-	}
+	return steps
+}
 
-	return CategoryOther
+// GetCleanDescription removes payment provider prefixes from description
+func GetCleanDescription(normalizedDesc string) string {
+	steps := CleanDescriptionSteps(normalizedDesc)
+	return steps[len(steps)-1].Result
 }
 
-// LoadStatements loads statements from a JSON file
+// DetectDetailedCategory detects granular category based on transaction
+// description using the built-in rules only
+func DetectDetailedCategory(normalizedDesc string) string {
+	return defaultCategorizer.Detect(&Transaction{}, normalizedDesc)
+}
+
+// defaultCategorizer holds the built-in rules
+var defaultCategorizer = NewCategorizer(nil)
+
+// LoadStatements loads statements from a file in any registered format
 func LoadStatements(filename string) ([]Statement, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var statements []Statement
-	err = json.Unmarshal(data, &statements)
-	if err != nil {
-		return nil, err
-	}
-
-	return statements, nil
+	return ParseStatements(data)
 }
 
-// CategorizeTransactions categorizes all transactions from statements
-func CategorizeTransactions(statements []Statement) CategorizedTransactions {
-	categorized := CategorizedTransactions{
-		ApplePay:    make([]Transaction, 0),
-		PayPal:      make([]Transaction, 0),
-		LinePay:     make([]Transaction, 0),
-		Jkopay:      make([]Transaction, 0),
-		ForeignFees: make([]Transaction, 0),
-		Other:       make([]Transaction, 0),
-	}
-
+// CategorizeTransactions categorizes all transactions from statements and
+// groups them by payment method
+func CategorizeTransactions(statements []Statement, categorizer *Categorizer) CategorizedTransactions {
 	applePayRegex := regexp.MustCompile(`^APE(\d{4})`)
 
 	for i := range statements {
@@ -157,24 +120,41 @@ func CategorizeTransactions(statements []Statement) CategorizedTransactions {
 
 			// Detect detailed category based on clean description
 			cleanDesc := GetCleanDescription(normalizedDesc)
-			detailedCategory := DetectDetailedCategory(cleanDesc)
+			categorizer.Categorize(tx, cleanDesc)
+		}
+	}
 
-			// Set the category field to the detailed category
-			tx.Category = detailedCategory
+	return GroupTransactions(statements)
+}
+
+// GroupTransactions sorts the transactions of statements into the payment
+// groups of the summary view. The groups hold copies, so they are built
+// again whenever merchants, overrides or edits change the transactions.
+func GroupTransactions(statements []Statement) CategorizedTransactions {
+	categorized := CategorizedTransactions{
+		ApplePay:    make([]Transaction, 0),
+		PayPal:      make([]Transaction, 0),
+		LinePay:     make([]Transaction, 0),
+		Jkopay:      make([]Transaction, 0),
+		ForeignFees: make([]Transaction, 0),
+		Other:       make([]Transaction, 0),
+	}
 
-			// Keep old categorization for summary view compatibility
+	for _, stmt := range statements {
+		for _, tx := range stmt.Transactions {
+			normalizedDesc := tx.NormalizedDescription
 			if strings.HasPrefix(normalizedDesc, "APE") {
-				categorized.ApplePay = append(categorized.ApplePay, *tx)
+				categorized.ApplePay = append(categorized.ApplePay, tx)
 			} else if strings.HasPrefix(normalizedDesc, "PAYPAL*") || strings.HasPrefix(normalizedDesc, "PP*") {
-				categorized.PayPal = append(categorized.PayPal, *tx)
+				categorized.PayPal = append(categorized.PayPal, tx)
 			} else if strings.HasPrefix(normalizedDesc, "連加*") {
-				categorized.LinePay = append(categorized.LinePay, *tx)
+				categorized.LinePay = append(categorized.LinePay, tx)
 			} else if strings.HasPrefix(normalizedDesc, "街口電支-") {
-				categorized.Jkopay = append(categorized.Jkopay, *tx)
+				categorized.Jkopay = append(categorized.Jkopay, tx)
 			} else if strings.HasPrefix(normalizedDesc, "國外交易手續費") {
-				categorized.ForeignFees = append(categorized.ForeignFees, *tx)
+				categorized.ForeignFees = append(categorized.ForeignFees, tx)
 			} else {
-				categorized.Other = append(categorized.Other, *tx)
+				categorized.Other = append(categorized.Other, tx)
 			}
 		}
 	}
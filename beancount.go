@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// beancountDate is the date layout of Beancount directives
+const beancountDate = "2006-01-02"
+
+// WriteBeancount writes a journal as a Beancount file
+func WriteBeancount(w io.Writer, j Journal) error {
+	bw := bufio.NewWriter(w)
+	for _, account := range j.Accounts {
+		fmt.Fprintf(bw, "%s open %s\n", j.Start.Format(beancountDate), account)
+	}
+	for _, pad := range j.Opening {
+		fmt.Fprintf(bw, "\n%s pad %s %s\n", j.Start.Format(beancountDate), pad.Account, pad.From)
+	}
+
+	for _, stmt := range j.Statements {
+		fmt.Fprintf(bw, "\n; Statement %s\n", stmt.Period)
+		for _, tx := range stmt.Transactions {
+			fmt.Fprintf(bw, "\n%s *", tx.Date.Format(beancountDate))
+			if tx.Payee != "" {
+				fmt.Fprintf(bw, " %s", beancountString(tx.Payee))
+			}
+			fmt.Fprintf(bw, " %s", beancountString(tx.Narration))
+			for _, tag := range tx.Tags {
+				fmt.Fprintf(bw, " #%s", beancountTag(tag))
+			}
+			bw.WriteString("\n")
+			for _, m := range tx.Meta {
+				fmt.Fprintf(bw, "  %s: %s\n", m.Key, beancountString(m.Value))
+			}
+			if !tx.Transacted.IsZero() && !tx.Transacted.Equal(tx.Date) {
+				fmt.Fprintf(bw, "  transaction-date: %s\n", tx.Transacted.Format(beancountDate))
+			}
+			for _, p := range tx.Postings {
+				fmt.Fprintf(bw, "  %s  %s", p.Account, beancountAmount(p.Amount))
+				if !p.Price.IsZero() {
+					fmt.Fprintf(bw, " @@ %s", beancountAmount(p.Price))
+				}
+				bw.WriteString("\n")
+			}
+		}
+
+		switch {
+		case !stmt.BalanceDate.IsZero():
+			fmt.Fprintf(bw, "\n%s balance %s  %s\n", stmt.BalanceDate.Format(beancountDate), stmt.Account, beancountAmount(stmt.Balance))
+		case stmt.SkipReason != "":
+			fmt.Fprintf(bw, "\n; No balance assertion: %s\n", stmt.SkipReason)
+		}
+	}
+	return bw.Flush()
+}
+
+// beancountAmount renders an amount with its currency, e.g. -430.00 TWD
+func beancountAmount(m Money) string {
+	return m.String() + " " + m.Currency
+}
+
+// beancountString quotes a string for Beancount
+func beancountString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s)
+	return `"` + s + `"`
+}
+
+// beancountTag replaces the characters Beancount does not allow in tags
+func beancountTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_/.", r):
+			return r
+		}
+		return '-'
+	}, tag)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterParser(camtParser{}, priorityFormat)
+}
+
+// camtParser reads ISO 20022 camt.053 bank-to-customer account statements
+type camtParser struct{}
+
+func (camtParser) Name() string {
+	return "camt.053"
+}
+
+// Detect looks for the camt.053 namespace or root element near the start of the input
+func (camtParser) Detect(r io.Reader) bool {
+	head, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("<BkToCstmrStmt"))
+}
+
+// camtAmount is an amount with its currency attribute
+type camtAmount struct {
+	Value string `xml:",chardata"`
+	Ccy   string `xml:"Ccy,attr"`
+}
+
+// camtDate holds either a date or a date-time
+type camtDate struct {
+	Dt   string `xml:"Dt"`
+	DtTm string `xml:"DtTm"`
+}
+
+func (d camtDate) String() string {
+	if d.Dt != "" {
+		return d.Dt
+	}
+	if len(d.DtTm) >= 10 {
+		return d.DtTm[:10]
+	}
+	return ""
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Amt          camtAmount `xml:"Amt"`
+	CdtDbtInd    string     `xml:"CdtDbtInd"`
+	BookgDt      camtDate   `xml:"BookgDt"`
+	ValDt        camtDate   `xml:"ValDt"`
+	AddtlNtryInf string     `xml:"AddtlNtryInf"`
+	Details      []struct {
+		InstdAmt camtAmount `xml:"AmtDtls>InstdAmt>Amt"`
+		Ustrd    []string   `xml:"RmtInf>Ustrd"`
+		Creditor string     `xml:"RltdPties>Cdtr>Nm"`
+		Debtor   string     `xml:"RltdPties>Dbtr>Nm"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	CreDtTm  string        `xml:"CreDtTm"`
+	ToDtTm   string        `xml:"FrToDt>ToDtTm"`
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	OtherID  string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// Parse maps each account's entries onto one statement per month. Credits
+// are positive and debits negative; the booking date becomes PostingDate and
+// the value date TxnDate.
+func (camtParser) Parse(r io.Reader) ([]Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("no Stmt elements found")
+	}
+
+	var statements []Statement
+	for _, s := range doc.Statements {
+		account := s.IBAN
+		if account == "" {
+			account = s.OtherID
+		}
+
+		stmt := Statement{
+			PaymentKey: "camt:" + account,
+			CardType:   "camt.053",
+		}
+
+		// Closing balance and statement date
+		endDate := ""
+		for _, bal := range s.Balances {
+			amount := signedAmount(bal.Amt.Value, bal.CdtDbtInd == "DBIT")
+			switch bal.Type {
+			case "OPBD", "PRCD":
+				stmt.PreBal = amount
+			case "CLBD":
+				stmt.CurTotAmt = amount
+				endDate = bal.Dt.String()
+			}
+			if s.Currency == "" {
+				s.Currency = bal.Amt.Ccy
+			}
+		}
+		stmt.Currency = s.Currency
+		if endDate == "" && len(s.ToDtTm) >= 10 {
+			endDate = s.ToDtTm[:10]
+		}
+		if endDate == "" && len(s.CreDtTm) >= 10 {
+			endDate = s.CreDtTm[:10]
+		}
+		if len(endDate) < 7 {
+			return nil, errors.New("statement " + s.ID + " has no closing date")
+		}
+		stmt.StmtDate = endDate
+		stmt.StmtYr = endDate[:4]
+		stmt.StmtMo = endDate[5:7]
+
+		for _, entry := range s.Entries {
+			debit := entry.CdtDbtInd == "DBIT"
+			amount := signedAmount(entry.Amt.Value, debit)
+
+			tx := Transaction{
+				Amount:      amount,
+				NtdAmount:   amount,
+				TxnDate:     entry.ValDt.String(),
+				PostingDate: entry.BookgDt.String(),
+				CardNo:      lastDigits(account, 4),
+			}
+			if tx.TxnDate == "" {
+				tx.TxnDate = tx.PostingDate
+			}
+
+			// Counterparty name first, then remittance information
+			var candidates []string
+			for _, d := range entry.Details {
+				if debit {
+					candidates = append(candidates, d.Creditor)
+				} else {
+					candidates = append(candidates, d.Debtor)
+				}
+				candidates = append(candidates, strings.Join(d.Ustrd, " "))
+
+				if d.InstdAmt.Ccy != "" && d.InstdAmt.Ccy != s.Currency {
+					tx.AmtCy = d.InstdAmt.Ccy
+					tx.Amount = signedAmount(d.InstdAmt.Value, debit)
+					tx.IsForeignTxn = true
+				}
+			}
+			candidates = append(candidates, entry.AddtlNtryInf)
+			for _, c := range candidates {
+				if c = strings.TrimSpace(c); c != "" {
+					tx.Description = c
+					break
+				}
+			}
+
+			stmt.Transactions = append(stmt.Transactions, tx)
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	return mergeMonthlyStatements(statements), nil
+}
+
+// signedAmount applies a debit/credit indicator to an unsigned amount.
+// Unparseable values are passed through and reported at load time.
+func signedAmount(value string, debit bool) string {
+	amount, err := ParseMoney(value, "")
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	if debit {
+		amount = amount.Neg()
+	}
+	return amount.String()
+}
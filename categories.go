@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// categorySeparator joins the levels of a hierarchical category path
+const categorySeparator = " > "
+
+// NormalizeCategory tidies a category path so "Food>Groceries" and
+// "Food > Groceries" are the same category. Empty levels are dropped, so
+// "Food > " is Food.
+func NormalizeCategory(cat string) string {
+	var parts []string
+	for _, p := range strings.Split(cat, ">") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, categorySeparator)
+}
+
+// CategoryParent returns the parent of a category path, or "" at the top level
+func CategoryParent(cat string) string {
+	i := strings.LastIndex(cat, categorySeparator)
+	if i < 0 {
+		return ""
+	}
+	return cat[:i]
+}
+
+// CategoryLeaf returns the last level of a category path
+func CategoryLeaf(cat string) string {
+	if i := strings.LastIndex(cat, categorySeparator); i >= 0 {
+		return cat[i+len(categorySeparator):]
+	}
+	return cat
+}
+
+// CategoryDepth returns the nesting level of a category, 0 at the top
+func CategoryDepth(cat string) int {
+	return strings.Count(cat, categorySeparator)
+}
+
+// CategoryAncestors returns the category and every parent, top level first
+func CategoryAncestors(cat string) []string {
+	var ancestors []string
+	for c := cat; c != ""; c = CategoryParent(c) {
+		ancestors = append([]string{c}, ancestors...)
+	}
+	return ancestors
+}
+
+// CategoryMatches reports whether a category falls under a filter: the
+// filter itself, any of its descendants, or anything for All
+func CategoryMatches(cat, filter string) bool {
+	return filter == CategoryAll || cat == filter || strings.HasPrefix(cat, filter+categorySeparator)
+}
+
+// CategoryDef describes a category: the value stored in
+// Transaction.Category, how it is displayed and its colour
+type CategoryDef struct {
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"` // Display name, defaults to Name
+	Color string `json:"color,omitempty"` // ANSI colour number or hex code
+}
+
+// CategorySet is the ordered set of categories offered in the TUI
+type CategorySet struct {
+	Categories []CategoryDef `json:"categories"`
+}
+
+// defaultCategoryColor is used for categories without a colour
+const defaultCategoryColor = "245"
+
+// DefaultCategories returns the built-in category set
+func DefaultCategories() CategorySet {
+	return CategorySet{Categories: []CategoryDef{
+		{Name: CategoryFood, Color: "214"},
+		{Name: CategoryTransport, Color: "39"},
+		{Name: CategoryShopping, Color: "170"},
+		{Name: CategoryTravel, Color: "45"},
+		{Name: CategoryUtilities, Color: "111"},
+		{Name: CategoryOther, Color: defaultCategoryColor},
+	}}
+}
+
+// LoadCategories loads the explicit categories file, or categories.json
+// from the configuration directory, falling back to the built-in set
+func LoadCategories(explicit string) (CategorySet, error) {
+	path := explicit
+	if path == "" {
+		path = configPath("categories.json")
+		if path == "" {
+			return DefaultCategories(), nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return DefaultCategories(), nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CategorySet{}, err
+	}
+
+	var set CategorySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return set, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range set.Categories {
+		set.Categories[i].Name = NormalizeCategory(set.Categories[i].Name)
+		c := set.Categories[i]
+		if c.Name == "" {
+			return set, fmt.Errorf("%s: category %d has no name", path, i+1)
+		}
+		if c.Name == CategoryAll {
+			return set, fmt.Errorf("%s: %q is reserved", path, CategoryAll)
+		}
+		if seen[c.Name] {
+			return set, fmt.Errorf("%s: duplicate category %q", path, c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	// Parents of nested categories become tabs of their own, and
+	// transactions that match no rule always need somewhere to go
+	for _, name := range set.Names() {
+		set.Ensure(CategoryAncestors(name)...)
+	}
+	set.Ensure(CategoryOther)
+	return set, nil
+}
+
+// Ensure adds categories that are not yet defined, such as categories
+// only named by rules. Nested categories are added with their parents and
+// placed after their siblings; others go before Other so it stays last.
+func (s *CategorySet) Ensure(names ...string) {
+	for _, name := range names {
+		if name == "" || name == CategoryAll {
+			continue
+		}
+		for _, cat := range CategoryAncestors(name) {
+			if _, ok := s.Lookup(cat); !ok {
+				s.insert(cat)
+			}
+		}
+	}
+}
+
+// insert adds a category after the last descendant of its parent
+func (s *CategorySet) insert(name string) {
+	pos := len(s.Categories)
+	if parent := CategoryParent(name); parent != "" {
+		for i, c := range s.Categories {
+			if CategoryMatches(c.Name, parent) {
+				pos = i + 1
+			}
+		}
+	} else if n := len(s.Categories); n > 0 && s.Categories[n-1].Name == CategoryOther && name != CategoryOther {
+		pos = n - 1
+	}
+
+	s.Categories = append(s.Categories, CategoryDef{})
+	copy(s.Categories[pos+1:], s.Categories[pos:])
+	s.Categories[pos] = CategoryDef{Name: name}
+}
+
+// Lookup returns the definition of a category
+func (s CategorySet) Lookup(name string) (CategoryDef, bool) {
+	for _, c := range s.Categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return CategoryDef{}, false
+}
+
+// Names returns the category names in display order
+func (s CategorySet) Names() []string {
+	names := make([]string, len(s.Categories))
+	for i, c := range s.Categories {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Label returns the display name of a category, defaulting to the last
+// level of its path
+func (s CategorySet) Label(name string) string {
+	if c, ok := s.Lookup(name); ok && c.Label != "" {
+		return c.Label
+	}
+	return CategoryLeaf(name)
+}
+
+// Color returns the colour of a category, inherited from the closest
+// ancestor that has one
+func (s CategorySet) Color(name string) string {
+	for c := name; c != ""; c = CategoryParent(c) {
+		if def, ok := s.Lookup(c); ok && def.Color != "" {
+			return def.Color
+		}
+	}
+	return defaultCategoryColor
+}
+
+// CategoryTotal is the rolled-up spend of a category and its descendants
+type CategoryTotal struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	Totals   Totals `json:"totals"`
+}
+
+// RollUp totals billed amounts per category, counting every transaction
+// towards its category and each ancestor. Results follow the order of the
+// set, with categories it does not define sorted at the end.
+func (s CategorySet) RollUp(txs []Transaction) []CategoryTotal {
+	byCategory := make(map[string]*CategoryTotal)
+	for _, tx := range txs {
+		for _, cat := range CategoryAncestors(tx.Category) {
+			total, ok := byCategory[cat]
+			if !ok {
+				total = &CategoryTotal{Category: cat, Totals: Totals{}}
+				byCategory[cat] = total
+			}
+			total.Count++
+			total.Totals.Add(tx.BilledAmount)
+		}
+	}
+
+	var result []CategoryTotal
+	for _, name := range s.Names() {
+		if total, ok := byCategory[name]; ok {
+			result = append(result, *total)
+			delete(byCategory, name)
+		}
+	}
+
+	var rest []string
+	for name := range byCategory {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		result = append(result, *byCategory[name])
+	}
+
+	return result
+}
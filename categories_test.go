@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCategory(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Food", "Food"},
+		{"Food>Groceries", "Food > Groceries"},
+		{" Food >  Groceries ", "Food > Groceries"},
+		{"Food > ", "Food"},
+		{"Food >> Groceries", "Food > Groceries"},
+		{" > ", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCategory(tt.in); got != tt.want {
+			t.Errorf("NormalizeCategory(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCategorySetEnsure(t *testing.T) {
+	set := DefaultCategories()
+	set.Ensure("Gifts", CategoryFood, CategoryAll, "", "Gifts", "Health")
+	want := "Food Transport Shopping Travel Utilities Gifts Health Other"
+	if got := strings.Join(set.Names(), " "); got != want {
+		t.Errorf("Names() = %s, want %s", got, want)
+	}
+}
+
+func TestLoadCategoriesAddsOther(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	data := `{"categories": [{"name": "Rent", "color": "1"}, {"name": "Food", "label": "Eating"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadCategories(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(set.Names(), " "); got != "Rent Food Other" {
+		t.Errorf("Names() = %s", got)
+	}
+	if set.Label(CategoryFood) != "Eating" || set.Color("Rent") != "1" || set.Color(CategoryOther) != defaultCategoryColor {
+		t.Errorf("labels and colours not kept: %+v", set)
+	}
+}
+
+func TestCategorySetEnsureNested(t *testing.T) {
+	set := DefaultCategories()
+	set.Ensure("Food > Groceries", "Travel > Flights > Long haul", "Food > Coffee", "Food > Groceries > Organic", "Pets > Vet")
+	want := []string{
+		"Food", "Food > Groceries", "Food > Groceries > Organic", "Food > Coffee",
+		"Transport", "Shopping",
+		"Travel", "Travel > Flights", "Travel > Flights > Long haul",
+		"Utilities", "Pets", "Pets > Vet", "Other",
+	}
+	if got := set.Names(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Names() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCategoryMatches(t *testing.T) {
+	tests := []struct {
+		cat, filter string
+		want        bool
+	}{
+		{"Food", "Food", true},
+		{"Food > Groceries", "Food", true},
+		{"Food > Groceries > Organic", "Food", true},
+		{"Food", "Food > Groceries", false},
+		{"Foodstuff", "Food", false},
+		{"Food > Groceries", "Food > Groc", false},
+		{"Travel", CategoryAll, true},
+	}
+	for _, tt := range tests {
+		if got := CategoryMatches(tt.cat, tt.filter); got != tt.want {
+			t.Errorf("CategoryMatches(%q, %q) = %v, want %v", tt.cat, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestCategorySetRollUp(t *testing.T) {
+	set := DefaultCategories()
+	set.Ensure("Food > Groceries")
+	txs := []Transaction{
+		{Category: "Food > Groceries", BilledAmount: twd(300)},
+		{Category: "Food > Groceries", BilledAmount: twd(-50)},
+		{Category: "Food", BilledAmount: twd(100)},
+		{Category: "Food > Groceries", BilledAmount: Money{Units: 120000, Currency: "EUR"}},
+		{Category: "Pets > Vet", BilledAmount: twd(800)},
+		{Category: CategoryTransport, BilledAmount: twd(40)},
+	}
+
+	var got []string
+	for _, total := range set.RollUp(txs) {
+		got = append(got, fmt.Sprintf("%s %d %s", total.Category, total.Count, total.Totals))
+	}
+	want := []string{
+		"Food 4 NT$350.00 + EUR 12.00",
+		"Food > Groceries 3 NT$250.00 + EUR 12.00",
+		"Transport 1 NT$40.00",
+		"Pets 1 NT$800.00",
+		"Pets > Vet 1 NT$800.00",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("RollUp() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
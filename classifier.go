@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Classifier is a naive Bayes model over description tokens, location and
+// foreign currency. It is trained on already categorized transactions and only
+// runs locally.
+type Classifier struct {
+	Docs       map[string]int            `json:"docs"`       // Training transactions per category
+	Features   map[string]map[string]int `json:"features"`   // Feature counts per category
+	Totals     map[string]int            `json:"totals"`     // Total feature count per category
+	Vocabulary map[string]bool           `json:"vocabulary"` // Every feature seen in training
+}
+
+// NewClassifier returns an untrained classifier
+func NewClassifier() *Classifier {
+	return &Classifier{
+		Docs:       make(map[string]int),
+		Features:   make(map[string]map[string]int),
+		Totals:     make(map[string]int),
+		Vocabulary: make(map[string]bool),
+	}
+}
+
+// classifierFeatures extracts the features of a transaction: description
+// tokens, plus its location and the original currency of a foreign charge
+func classifierFeatures(tx *Transaction, cleanDesc string) []string {
+	var features []string
+
+	tokens := strings.FieldsFunc(strings.ToUpper(cleanDesc), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, tok := range tokens {
+		runes := []rune(tok)
+		if len(runes) < 2 || strings.Trim(tok, "0123456789") == "" {
+			continue
+		}
+		features = append(features, "w:"+tok)
+
+		// Chinese descriptions are not split by spaces, so bigrams give
+		// the model shorter pieces to match on
+		if unicode.Is(unicode.Han, runes[0]) {
+			for i := 0; i+2 <= len(runes) && len(runes) > 2; i++ {
+				features = append(features, "w:"+string(runes[i:i+2]))
+			}
+		}
+	}
+
+	if loc := strings.ToUpper(strings.TrimSpace(tx.TxnLoc)); loc != "" {
+		features = append(features, "loc:"+loc)
+	}
+	// The billing currency is shared by almost every transaction and
+	// would say nothing about the category
+	if cur := tx.OriginalAmount.Currency; cur != "" && cur != tx.BilledAmount.Currency {
+		features = append(features, "cur:"+cur)
+	}
+
+	return features
+}
+
+// Train adds categorized transactions to the model. Transactions left in
+// Other carry no signal and are skipped. It returns the number used.
+func (c *Classifier) Train(statements []Statement) int {
+	trained := 0
+	for _, stmt := range statements {
+		for i := range stmt.Transactions {
+			tx := &stmt.Transactions[i]
+			if tx.Category == "" || tx.Category == CategoryOther {
+				continue
+			}
+			features := classifierFeatures(tx, GetCleanDescription(tx.NormalizedDescription))
+			if len(features) == 0 {
+				continue
+			}
+
+			counts, ok := c.Features[tx.Category]
+			if !ok {
+				counts = make(map[string]int)
+				c.Features[tx.Category] = counts
+			}
+			for _, f := range features {
+				counts[f]++
+				c.Vocabulary[f] = true
+			}
+			c.Totals[tx.Category] += len(features)
+			c.Docs[tx.Category]++
+			trained++
+		}
+	}
+	return trained
+}
+
+// Categories returns the categories the model can predict, sorted
+func (c *Classifier) Categories() []string {
+	categories := make([]string, 0, len(c.Docs))
+	for cat := range c.Docs {
+		categories = append(categories, cat)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Predict returns the most likely category for a transaction and the
+// model's confidence in it, between 0 and 1. Features never seen in
+// training are ignored. Without a known description or location feature
+// there is no prediction, since a currency alone is no evidence.
+func (c *Classifier) Predict(tx *Transaction, cleanDesc string) (string, float64) {
+	var known []string
+	evidence := false
+	for _, f := range classifierFeatures(tx, cleanDesc) {
+		if c.Vocabulary[f] {
+			known = append(known, f)
+			evidence = evidence || !strings.HasPrefix(f, "cur:")
+		}
+	}
+	if !evidence {
+		return "", 0
+	}
+
+	docs := 0
+	for _, n := range c.Docs {
+		docs += n
+	}
+	vocab := float64(len(c.Vocabulary))
+
+	// Log-probabilities with add-one smoothing
+	categories := c.Categories()
+	scores := make([]float64, len(categories))
+	for i, cat := range categories {
+		score := math.Log(float64(c.Docs[cat]) / float64(docs))
+		for _, f := range known {
+			score += math.Log((float64(c.Features[cat][f]) + 1) / (float64(c.Totals[cat]) + vocab))
+		}
+		scores[i] = score
+	}
+
+	// Normalise into probabilities and pick the best
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - scores[best])
+	}
+
+	return categories[best], 1 / sum
+}
+
+// LoadClassifier reads a trained model from the explicit file, or
+// classifier.json in the configuration directory. It returns nil without
+// an error when no model has been trained yet.
+func LoadClassifier(explicit string) (*Classifier, error) {
+	path := classifierPath(explicit)
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && explicit == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewClassifier()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the model to the explicit file or the configuration directory
+// and returns the path used
+func (c *Classifier) Save(explicit string) (string, error) {
+	path := classifierPath(explicit)
+	if path == "" {
+		return "", errors.New("no classifier file: set -classifier or a configuration directory")
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// classifierPath returns where the model is stored
+func classifierPath(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return configPath("classifier.json")
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+// classifierTx builds a transaction billed in TWD for classifier tests
+func classifierTx(desc, loc, category, currency string) Transaction {
+	return Transaction{
+		NormalizedDescription: desc,
+		TxnLoc:                loc,
+		Category:              category,
+		OriginalAmount:        Money{Units: 100 * moneyScale, Currency: currency},
+		BilledAmount:          Money{Units: 100 * moneyScale, Currency: "TWD"},
+	}
+}
+
+func TestClassifierPredict(t *testing.T) {
+	c := NewClassifier()
+	c.Train([]Statement{{Transactions: []Transaction{
+		classifierTx("STARBUCKS COFFEE", "TAIPEI", CategoryFood, "TWD"),
+		classifierTx("STARBUCKS RESERVE", "TAIPEI", CategoryFood, "TWD"),
+		classifierTx("UBER TRIP", "AMSTERDAM", CategoryTransport, "EUR"),
+	}}})
+
+	tests := []struct {
+		name     string
+		tx       Transaction
+		category string
+	}{
+		{"known description", classifierTx("STARBUCKS LATTE", "", "", "TWD"), CategoryFood},
+		{"known location", classifierTx("CAFE", "AMSTERDAM", "", "TWD"), CategoryTransport},
+		{"unseen description", classifierTx("保險費", "", "", "TWD"), ""},
+		{"unseen description in a foreign currency", classifierTx("MUSEUM", "", "", "EUR"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, confidence := c.Predict(&tt.tx, tt.tx.NormalizedDescription)
+			if category != tt.category {
+				t.Errorf("Predict() = %q (%.2f), want %q", category, confidence, tt.category)
+			}
+			if tt.category == "" && confidence != 0 {
+				t.Errorf("Predict() confidence = %.2f without a prediction", confidence)
+			}
+		})
+	}
+}
+
+func TestClassifierFeaturesSkipBillingCurrency(t *testing.T) {
+	domestic := classifierTx("SHOP", "", "", "TWD")
+	for _, f := range classifierFeatures(&domestic, "SHOP") {
+		if f == "cur:TWD" {
+			t.Errorf("features of a domestic charge include %q", f)
+		}
+	}
+
+	foreign := classifierTx("SHOP", "", "", "EUR")
+	found := false
+	for _, f := range classifierFeatures(&foreign, "SHOP") {
+		found = found || f == "cur:EUR"
+	}
+	if !found {
+		t.Error("features of a foreign charge miss cur:EUR")
+	}
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a subcommand of the statements binary
+type command struct {
+	name    string
+	args    string // Argument synopsis for the usage text
+	summary string
+	run     func(c command, args []string) error
+}
+
+// commands lists the subcommands in the order they appear in the usage text
+var commands = []command{
+	{"train", "[flags] <inputs>...", "train the category classifier on categorized transactions", runTrain},
+	{"explain", "[flags] <description>", "show how a transaction description is categorized", runExplain},
+	{"rules", "check [flags] <inputs>...", "report rule coverage, conflicts and rules that never fire", runRules},
+	{"query", "[flags] <expression> <inputs>...", "print the transactions matching a filter expression", runQuery},
+	{"summary", "[flags] <inputs>...", "print the totals of the summary view", runSummary},
+	{"list", "[flags] <inputs>...", "print the transactions of each statement as the statements view lists them", runList},
+	{"statements", "[flags] <inputs>...", "print the loaded statements with their totals", runStatements},
+	{"categories", "[flags] <inputs>...", "print the categories with the spending in each", runCategories},
+	{"export", "[flags] <inputs>...", "write the transactions of each statement as CSV, JSON, Excel, Beancount or Ledger", runExport},
+	{"report", "-html <file> [flags] <inputs>...", "write a self-contained HTML report to share", runReport},
+}
+
+// findCommand returns the subcommand with the given name
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// printCommands lists the subcommands for the main usage text
+func printCommands() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+// newCommandFlags creates the flag set of a subcommand with the shared
+// loading flags registered
+func newCommandFlags(c command, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: statements %s %s\n\n%s\n\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runTrain fits the classifier on transactions categorized by rules and
+// overrides, never on its own earlier suggestions
+func runTrain(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadSession(opts, fs.Args(), false)
+	if err != nil {
+		return err
+	}
+	if s.report.HasIssues() {
+		fmt.Fprint(os.Stderr, s.report.String())
+	}
+
+	classifier := NewClassifier()
+	trained := classifier.Train(s.statements)
+	if trained == 0 {
+		return errors.New("no categorized transactions to train on")
+	}
+
+	path, err := classifier.Save(opts.classifier)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Trained on %d transactions in %d categories, saved to %s\n",
+		trained, len(classifier.Docs), path)
+	return nil
+}
+
+// runExplain traces the categorization of a single description through
+// the cleaning steps, rules and classifier
+func runExplain(c command, args []string) error {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	rulesFile := fs.String("rules", "", "categorization rules file (JSON)")
+	classifierFile := fs.String("classifier", "", "trained category classifier (JSON)")
+	minConfidence := fs.Float64("min-confidence", 0.6, "minimum classifier confidence for a suggested category")
+	location := fs.String("location", "", "transaction location")
+	currency := fs.String("currency", "", "original currency (default the billing currency)")
+	billedCurrency := fs.String("billed-currency", "TWD", "billing currency")
+	amount := fs.String("amount", "0", "billed amount, in the billing currency")
+	card := fs.String("card", "", "card number")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: statements %s %s\n\n%s\n\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	userRules, err := LoadUserRules(*rulesFile)
+	if err != nil {
+		return fmt.Errorf("loading rules: %w", err)
+	}
+	categorizer := NewCategorizer(userRules)
+	categorizer.MinConfidence = *minConfidence
+	if categorizer.Classifier, err = LoadClassifier(*classifierFile); err != nil {
+		return fmt.Errorf("loading classifier: %w", err)
+	}
+
+	billed, err := ParseMoney(*amount, strings.ToUpper(*billedCurrency))
+	if err != nil {
+		return err
+	}
+	// The original amount of a foreign purchase is unknown, only its currency
+	original := billed
+	if cur := strings.ToUpper(*currency); cur != "" && cur != billed.Currency {
+		original = Money{Currency: cur}
+	}
+	tx := Transaction{
+		Description:    strings.Join(fs.Args(), " "),
+		TxnLoc:         *location,
+		CardNo:         *card,
+		OriginalAmount: original,
+		BilledAmount:   billed,
+	}
+
+	fmt.Println(categorizer.Explain(tx, nil))
+	return nil
+}
+
+// runRules dispatches the rules subcommands; check is the only one
+func runRules(c command, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintf(os.Stderr, "Usage: statements %s %s\n", c.name, c.args)
+		os.Exit(2)
+	}
+
+	var opts options
+	fs := newCommandFlags(command{name: "rules check", args: "[flags] <inputs>...", summary: c.summary}, &opts)
+	fs.Parse(args[1:])
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadSession(opts, fs.Args(), false)
+	if err != nil {
+		return err
+	}
+	if s.report.HasIssues() {
+		fmt.Fprint(os.Stderr, s.report.String())
+	}
+
+	CheckRules(s.statements, s.categorizer).Write(os.Stdout)
+	return nil
+}
+
+// loadCommandSession loads the inputs of a subcommand, printing its usage
+// when there are none and any load issues on stderr
+func loadCommandSession(fs *flag.FlagSet, opts options, inputs []string) (*session, error) {
+	if len(inputs) < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadSession(opts, inputs, true)
+	if err != nil {
+		return nil, err
+	}
+	if s.report.HasIssues() {
+		fmt.Fprint(os.Stderr, s.report.String())
+	}
+	return s, nil
+}
+
+// writeJSON prints a value as indented JSON
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// runQuery prints the transactions of every statement that match a filter
+// expression
+func runQuery(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	sortBy := fs.String("sort", "date", "sort by date, amount, location, category or merchant")
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	query, err := ParseQuery(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("in query: %w", err)
+	}
+	mode, err := parseSortMode(*sortBy)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadCommandSession(fs, opts, fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	rows := MatchingRows(s.statements, query, mode)
+	if *jsonOut {
+		return writeJSON(rows)
+	}
+	WriteRows(os.Stdout, rows)
+	return nil
+}
+
+// runSummary prints the aggregates of the summary view
+func runSummary(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	summary := BuildSummary(s.statements, s.categorized, s.categories)
+	if *jsonOut {
+		return writeJSON(summary)
+	}
+	summary.Write(os.Stdout, s.categories)
+	return nil
+}
+
+// rowFlags select the transaction rows of list and export
+type rowFlags struct {
+	statement string
+	filter    string
+	sort      string
+}
+
+// register adds the row selection flags to a flag set
+func (f *rowFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.statement, "statement", "", "only include the statements of this month (YYYY/MM)")
+	fs.StringVar(&f.filter, "filter", "", "only include transactions matching this filter expression")
+	fs.StringVar(&f.sort, "sort", "date", "sort by date, amount, location, category or merchant")
+}
+
+// rows builds the rows of the statements view for every selected statement
+func (f rowFlags) rows(statements []Statement) ([]TransactionRow, error) {
+	query, err := ParseQuery(f.filter)
+	if err != nil {
+		return nil, fmt.Errorf("in filter: %w", err)
+	}
+	mode, err := parseSortMode(f.sort)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := f.selected(statements)
+	if err != nil {
+		return nil, err
+	}
+	rows := []TransactionRow{}
+	for _, i := range selected {
+		rows = append(rows, StatementRows(statements, i, query, mode)...)
+	}
+	return rows, nil
+}
+
+// selected returns the indexes of the statements of the -statement month,
+// or of every statement
+func (f rowFlags) selected(statements []Statement) ([]int, error) {
+	var selected []int
+	for i, stmt := range statements {
+		if f.statement != "" && fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo) != strings.ReplaceAll(f.statement, "-", "/") {
+			continue
+		}
+		selected = append(selected, i)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no statement for %s", f.statement)
+	}
+	return selected, nil
+}
+
+// runList prints the rows of the statements view for every statement, or
+// those of one month
+func runList(c command, args []string) error {
+	var opts options
+	var selection rowFlags
+	fs := newCommandFlags(c, &opts)
+	selection.register(fs)
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+	rows, err := selection.rows(s.statements)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		return writeJSON(rows)
+	}
+	WriteRows(os.Stdout, rows)
+	return nil
+}
+
+// runExport writes the rows of the statements view as CSV or JSON, or the
+// statements as an Excel workbook or a Beancount or Ledger journal
+func runExport(c command, args []string) error {
+	var opts options
+	var selection rowFlags
+	fs := newCommandFlags(c, &opts)
+	selection.register(fs)
+	output := fs.String("o", "-", "output file, - for stdout")
+	format := fs.String("format", "", "csv, json, xlsx, beancount or ledger (default from the output file extension, else csv)")
+	accountsPath := fs.String("accounts", "", "account mapping file for beancount and ledger (JSON)")
+	fs.Parse(args)
+
+	// Fail on a bad format before loading anything
+	kind, err := exportFormat(*format, *output)
+	if err != nil {
+		return err
+	}
+	journal := kind == "beancount" || kind == "ledger"
+	if journal && (selection.filter != "" || opts.from != "" || opts.to != "") {
+		return fmt.Errorf("%s exports whole statements; -filter, -from and -to cannot be used", kind)
+	}
+	if kind == "xlsx" && (selection.filter != "" || selection.statement != "") {
+		return errors.New("xlsx exports every loaded statement; -filter and -statement cannot be used")
+	}
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+	if journal {
+		return exportJournal(*output, kind, *accountsPath, selection, s.statements)
+	}
+	if kind == "xlsx" {
+		summary := BuildSummary(s.statements, s.categorized, s.categories)
+		sheets := BuildWorkbook(s.statements, summary, s.categories)
+		if err := createOutput(*output, func(w io.Writer) error { return WriteXLSX(w, sheets) }); err != nil {
+			return err
+		}
+		if *output != "-" {
+			fmt.Fprintf(os.Stderr, "Exported %d statements to %s\n", len(s.statements), *output)
+		}
+		return nil
+	}
+	rows, err := selection.rows(s.statements)
+	if err != nil {
+		return err
+	}
+
+	if err := ExportFile(*output, *format, rows); err != nil {
+		return err
+	}
+	if *output != "-" {
+		fmt.Fprintf(os.Stderr, "Exported %d rows to %s\n", len(rows), *output)
+	}
+	return nil
+}
+
+// exportJournal writes the selected statements as a Beancount journal, or
+// adds those not yet in a Ledger journal to it
+func exportJournal(output, format, accountsPath string, selection rowFlags, statements []Statement) error {
+	accounts, err := LoadAccounts(accountsPath)
+	if err != nil {
+		return err
+	}
+	selected, err := selection.selected(statements)
+	if err != nil {
+		return err
+	}
+	var stmts []Statement
+	for _, i := range selected {
+		stmts = append(stmts, statements[i])
+	}
+	journal, err := BuildJournal(stmts, accounts)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	if format == "ledger" {
+		n, err = ExportLedgerFile(output, journal)
+	} else {
+		err = createOutput(output, func(w io.Writer) error { return WriteBeancount(w, journal) })
+		for _, stmt := range journal.Statements {
+			n += len(stmt.Transactions)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if output != "-" {
+		fmt.Fprintf(os.Stderr, "Exported %d transactions to %s\n", n, output)
+	}
+	return nil
+}
+
+// runStatements prints one line per loaded statement
+func runStatements(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	infos := make([]StatementInfo, len(s.statements))
+	for i, stmt := range s.statements {
+		infos[i] = NewStatementInfo(stmt)
+	}
+	if *jsonOut {
+		return writeJSON(infos)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Statement\tDue\tTotal\tMinimum\tTransactions\t")
+	for i, info := range infos {
+		stmt := s.statements[i]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t\n", info.Period, formatDate(stmt.PaymentDue, stmt.PmtDue),
+			info.Total.Display(), info.MinPayment.Display(), info.Transactions)
+	}
+	return w.Flush()
+}
+
+// runCategories prints the category tree with the purchases in each
+// category and its subcategories
+func runCategories(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	summary := BuildSummary(s.statements, s.categorized, s.categories)
+	spend := make(map[string]CategoryTotal)
+	for _, total := range summary.Categories {
+		spend[total.Category] = total
+	}
+
+	type categoryInfo struct {
+		Name   string `json:"name"`
+		Label  string `json:"label"`
+		Color  string `json:"color"`
+		Count  int    `json:"count"`
+		Totals Totals `json:"totals"`
+	}
+	var infos []categoryInfo
+	for _, name := range s.categories.Names() {
+		total := spend[name]
+		if total.Totals == nil {
+			total.Totals = Totals{}
+		}
+		infos = append(infos, categoryInfo{name, s.categories.Label(name), s.categories.Color(name), total.Count, total.Totals})
+	}
+	if *jsonOut {
+		return writeJSON(infos)
+	}
+
+	for _, info := range infos {
+		indent := strings.Repeat("  ", CategoryDepth(info.Name))
+		fmt.Printf("%s%s: %d transactions, Total: %s\n", indent, info.Label, info.Count, info.Totals)
+	}
+	return nil
+}
+
+// parseSortMode parses the name of a sort mode
+func parseSortMode(name string) (sortMode, error) {
+	for mode, label := range sortLabels {
+		if strings.EqualFold(name, label) {
+			return sortMode(mode), nil
+		}
+	}
+	return sortByDate, fmt.Errorf("unknown sort %q", name)
+}
+
+// runReport writes the summary, statement totals, category charts and
+// transactions as a single HTML file
+func runReport(c command, args []string) error {
+	var opts options
+	fs := newCommandFlags(c, &opts)
+	output := fs.String("html", "", "HTML file to write, - for stdout")
+	fs.Parse(args)
+	if *output == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadCommandSession(fs, opts, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	report := BuildReport(s.statements, s.categorized, s.categories)
+	if err := createOutput(*output, report.WriteHTML); err != nil {
+		return err
+	}
+	if *output != "-" {
+		fmt.Fprintf(os.Stderr, "Wrote report of %d statements to %s\n", len(s.statements), *output)
+	}
+	return nil
+}
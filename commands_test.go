@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// captureStdout returns what run prints to stdout
+func captureStdout(t *testing.T, run func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	runErr := run()
+	w.Close()
+	data := <-out
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	return string(data)
+}
+
+// checkGolden compares output with a golden file, or rewrites it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (rerun with -update after checking it):\n%s", path, got)
+	}
+}
+
+func TestCommandsJSONOutput(t *testing.T) {
+	// Keep the user's rules, categories and overrides out of the output
+	t.Setenv("STATEMENTS_CONFIG_DIR", t.TempDir())
+	input := filepath.Join("testdata", "hsbc", "2025-03.json")
+
+	tests := []struct {
+		golden string
+		run    func(command, []string) error
+		args   []string
+	}{
+		{"list.json", runList, []string{"-json", input}},
+		{"list-filtered.json", runList, []string{"-json", "-filter", "amount>400", "-sort", "amount", input}},
+		{"summary.json", runSummary, []string{"-json", input}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			c, _ := findCommand("list")
+			out := captureStdout(t, func() error { return tt.run(c, tt.args) })
+			checkGolden(t, tt.golden, out)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// configDir returns the directory holding user configuration such as
+// CSV profiles. STATEMENTS_CONFIG_DIR overrides the platform default.
+func configDir() string {
+	if dir := os.Getenv("STATEMENTS_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "statements")
+}
+
+// configPath returns the path of a file or directory inside configDir,
+// or an empty string when no configuration directory is available
+func configPath(name string) string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sign conventions for CSV amount columns
+const (
+	SignExpensePositive = "expense-positive" // Purchases are positive, refunds negative
+	SignExpenseNegative = "expense-negative" // Purchases are negative, refunds positive
+)
+
+// CSVProfile maps the columns of a bank's CSV export onto transactions
+type CSVProfile struct {
+	Name      string `json:"name"`
+	Delimiter string `json:"delimiter"` // Defaults to ","
+	SkipLines int    `json:"skipLines"` // Lines before the header row
+
+	DateColumn         string `json:"dateColumn"`
+	PostingDateColumn  string `json:"postingDateColumn"`
+	AmountColumn       string `json:"amountColumn"`
+	BilledAmountColumn string `json:"billedAmountColumn"` // Amount in the account currency, if exported
+	CurrencyColumn     string `json:"currencyColumn"`
+	DescriptionColumn  string `json:"descriptionColumn"`
+	LocationColumn     string `json:"locationColumn"`
+	CardColumn         string `json:"cardColumn"`
+
+	DateFormat       string `json:"dateFormat"`       // YYYY or YY, MM and DD with separators, e.g. "DD/MM/YYYY"
+	DecimalSeparator string `json:"decimalSeparator"` // "." or ","
+	SignConvention   string `json:"signConvention"`   // SignExpensePositive or SignExpenseNegative
+	Currency         string `json:"currency"`         // Account currency, defaults to TWD
+	StatementDay     int    `json:"statementDay"`     // Billing cycle closing day, 0 for calendar months
+	CardNo           string `json:"cardNo"`           // Card number when there is no card column
+}
+
+// LoadCSVProfile reads a single profile from a JSON file
+func LoadCSVProfile(filename string) (CSVProfile, error) {
+	var profile CSVProfile
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return profile, err
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("%s: %w", filename, err)
+	}
+	if profile.Name == "" {
+		profile.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if err := profile.validate(); err != nil {
+		return profile, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return profile, nil
+}
+
+// LoadCSVProfiles loads the explicit profile, if any, followed by every
+// profile saved in the csv directory of the configuration directory
+func LoadCSVProfiles(explicit string) ([]CSVProfile, error) {
+	var profiles []CSVProfile
+
+	if explicit != "" {
+		profile, err := LoadCSVProfile(explicit)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	dir := configPath("csv")
+	if dir == "" {
+		return profiles, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		profile, err := LoadCSVProfile(file)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// RegisterCSVProfiles registers one CSV parser per profile. A profile
+// mapping more columns is more specific and is tried first; registering a
+// profile name again replaces it.
+func RegisterCSVProfiles(profiles []CSVProfile) {
+	for _, profile := range profiles {
+		c := csvParser{profile: profile}
+		RegisterParser(c, len(c.mappedColumns()))
+	}
+}
+
+func (p CSVProfile) validate() error {
+	if p.DateColumn == "" || p.AmountColumn == "" || p.DescriptionColumn == "" {
+		return errors.New("profile needs dateColumn, amountColumn and descriptionColumn")
+	}
+	if len([]rune(p.Delimiter)) > 1 {
+		return fmt.Errorf("delimiter must be a single character, got %q", p.Delimiter)
+	}
+	switch p.SignConvention {
+	case "", SignExpensePositive, SignExpenseNegative:
+	default:
+		return fmt.Errorf("unknown sign convention %q", p.SignConvention)
+	}
+	switch p.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return fmt.Errorf("unknown decimal separator %q", p.DecimalSeparator)
+	}
+	if p.DateFormat != "" {
+		rest := strings.NewReplacer("YYYY", "Y", "YY", "Y").Replace(p.DateFormat)
+		for _, token := range []string{"Y", "MM", "DD"} {
+			if strings.Count(rest, token) != 1 {
+				return fmt.Errorf("date format %q needs YYYY or YY, MM and DD once each", p.DateFormat)
+			}
+			rest = strings.Replace(rest, token, "", 1)
+		}
+		if i := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }); i >= 0 {
+			return fmt.Errorf("date format %q: unknown token %q, use YYYY, YY, MM and DD", p.DateFormat, rest[i:])
+		}
+	}
+	return nil
+}
+
+// layout converts the profile date format into a Go time layout
+func (p CSVProfile) layout() string {
+	if p.DateFormat == "" {
+		return "2006-01-02"
+	}
+	replacer := strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+	return replacer.Replace(p.DateFormat)
+}
+
+func (p CSVProfile) currency() string {
+	if p.Currency == "" {
+		return "TWD"
+	}
+	return strings.ToUpper(p.Currency)
+}
+
+// parseAmount normalizes a CSV amount into a plain decimal string with the
+// expense-positive sign used by statements
+func (p CSVProfile) parseAmount(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", nil
+	}
+
+	// Accounting style negatives, e.g. (12.50)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	s = strings.ReplaceAll(s, " ", "")
+	if p.DecimalSeparator == "," {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	amount, err := ParseMoney(s, "")
+	if err != nil {
+		return "", fmt.Errorf("invalid amount %q", raw)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	if p.SignConvention == SignExpenseNegative {
+		amount = amount.Neg()
+	}
+
+	return amount.String(), nil
+}
+
+// billingMonth returns the statement month a transaction date belongs to
+func (p CSVProfile) billingMonth(date time.Time) time.Time {
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if p.StatementDay > 0 && date.Day() > p.StatementDay {
+		month = month.AddDate(0, 1, 0)
+	}
+	return month
+}
+
+// statementDate returns the closing date of a billing month
+func (p CSVProfile) statementDate(month time.Time) time.Time {
+	if p.StatementDay > 0 {
+		return time.Date(month.Year(), month.Month(), p.StatementDay, 0, 0, 0, 0, time.UTC)
+	}
+	return month.AddDate(0, 1, -1)
+}
+
+// csvParser imports CSV exports described by a profile
+type csvParser struct {
+	profile CSVProfile
+}
+
+func (c csvParser) Name() string {
+	return "CSV (" + c.profile.Name + ")"
+}
+
+func (c csvParser) reader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	if c.profile.Delimiter != "" {
+		reader.Comma = []rune(c.profile.Delimiter)[0]
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+// header reads up to the header row and returns a column name -> index map
+func (c csvParser) header(reader *csv.Reader) (map[string]int, error) {
+	for i := 0; i < c.profile.SkipLines; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, err
+		}
+	}
+
+	record, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range record {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns, nil
+}
+
+// Detect checks that every mapped column is present in the header row
+func (c csvParser) Detect(r io.Reader) bool {
+	columns, err := c.header(c.reader(r))
+	if err != nil {
+		return false
+	}
+
+	for _, name := range c.mappedColumns() {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c csvParser) mappedColumns() []string {
+	var names []string
+	for _, name := range []string{
+		c.profile.DateColumn,
+		c.profile.PostingDateColumn,
+		c.profile.AmountColumn,
+		c.profile.BilledAmountColumn,
+		c.profile.CurrencyColumn,
+		c.profile.DescriptionColumn,
+		c.profile.LocationColumn,
+		c.profile.CardColumn,
+	} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Parse groups rows into one synthetic statement per billing month
+func (c csvParser) Parse(r io.Reader) ([]Statement, error) {
+	reader := c.reader(r)
+	columns, err := c.header(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(record []string, name string) string {
+		if name == "" {
+			return ""
+		}
+		i, ok := columns[strings.ToLower(name)]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	layout := c.profile.layout()
+	accountCy := c.profile.currency()
+	byMonth := make(map[time.Time]*Statement)
+	totals := make(map[time.Time]Money)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Quoted fields can span lines, so ask the reader where the row starts
+		line, _ := reader.FieldPos(0)
+
+		rawDate := field(record, c.profile.DateColumn)
+		if rawDate == "" && field(record, c.profile.AmountColumn) == "" {
+			continue // Blank or trailer row
+		}
+
+		date, err := time.Parse(layout, rawDate)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, rawDate)
+		}
+
+		postingDate := date
+		if raw := field(record, c.profile.PostingDateColumn); raw != "" {
+			if postingDate, err = time.Parse(layout, raw); err != nil {
+				return nil, fmt.Errorf("line %d: invalid posting date %q", line, raw)
+			}
+		}
+
+		amount, err := c.profile.parseAmount(field(record, c.profile.AmountColumn))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		billed, err := c.profile.parseAmount(field(record, c.profile.BilledAmountColumn))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// Transactions in the account currency leave the currency blank,
+		// matching the HSBC export for domestic transactions
+		currency := strings.ToUpper(field(record, c.profile.CurrencyColumn))
+		foreign := currency != "" && currency != accountCy
+		if !foreign {
+			currency = ""
+			if billed == "" {
+				billed = amount
+			}
+		}
+
+		cardNo := field(record, c.profile.CardColumn)
+		if cardNo == "" {
+			cardNo = c.profile.CardNo
+		}
+
+		tx := Transaction{
+			Amount:       amount,
+			Description:  field(record, c.profile.DescriptionColumn),
+			AmtCy:        currency,
+			TxnLoc:       field(record, c.profile.LocationColumn),
+			TxnDate:      date.Format("2006-01-02"),
+			PostingDate:  postingDate.Format("2006-01-02"),
+			NtdAmount:    billed,
+			IsForeignTxn: foreign,
+			CardNo:       cardNo,
+		}
+
+		month := c.profile.billingMonth(postingDate)
+		stmt, ok := byMonth[month]
+		if !ok {
+			stmt = &Statement{
+				PaymentKey: csvKeyPrefix + c.profile.Name,
+				CardType:   c.profile.Name,
+				StmtYr:     month.Format("2006"),
+				StmtMo:     month.Format("01"),
+				StmtDate:   c.profile.statementDate(month).Format("2006-01-02"),
+				Currency:   accountCy,
+			}
+			byMonth[month] = stmt
+		}
+		stmt.Transactions = append(stmt.Transactions, tx)
+
+		value, err := ParseMoney(billed, accountCy)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		totals[month] = totals[month].Add(value)
+	}
+
+	months := make([]time.Time, 0, len(byMonth))
+	for month := range byMonth {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].After(months[j]) })
+
+	statements := make([]Statement, 0, len(months))
+	for _, month := range months {
+		stmt := byMonth[month]
+		stmt.CurTotAmt = totals[month].String()
+		statements = append(statements, *stmt)
+	}
+
+	return statements, nil
+}
+
+// csvKeyPrefix starts the payment key of statements grouped from CSV rows
+const csvKeyPrefix = "csv:"
+
+// isCSVStatement reports whether a statement was grouped from CSV rows, so
+// that exports of the same profile and billing month can be merged
+func isCSVStatement(stmt Statement) bool {
+	return strings.HasPrefix(stmt.PaymentKey, csvKeyPrefix)
+}
+
+// mergeCSVStatement adds the rows of another export of the same billing month
+// to a statement and updates its total. Rows the statement already has are
+// skipped, so overlapping exports are not counted twice. It returns the
+// number of rows added.
+func mergeCSVStatement(into *Statement, other Statement) (int, error) {
+	total, err := ParseMoney(into.CurTotAmt, into.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]int)
+	for _, tx := range into.Transactions {
+		seen[csvRowKey(tx)]++
+	}
+	added := 0
+	for _, tx := range other.Transactions {
+		if key := csvRowKey(tx); seen[key] > 0 {
+			seen[key]--
+			continue
+		}
+		value, err := ParseMoney(tx.NtdAmount, into.Currency)
+		if err != nil {
+			return added, fmt.Errorf("%s: %w", tx.Description, err)
+		}
+		total = total.Add(value)
+		into.Transactions = append(into.Transactions, tx)
+		added++
+	}
+
+	into.CurTotAmt = total.String()
+	return added, nil
+}
+
+// csvRowKey identifies an imported row by the fields read from its columns
+func csvRowKey(tx Transaction) string {
+	return strings.Join([]string{
+		tx.TxnDate, tx.PostingDate, tx.Description, tx.TxnLoc, tx.CardNo,
+		tx.Amount, tx.AmtCy, tx.NtdAmount,
+	}, "\x00")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCSVExports writes CSV exports in the layout of the test profile,
+// registering that profile
+func writeCSVExports(t *testing.T, exports ...string) []string {
+	t.Helper()
+	RegisterCSVProfiles([]CSVProfile{{
+		Name:              "testbank",
+		DateColumn:        "Booked On",
+		AmountColumn:      "Charged",
+		DescriptionColumn: "Details",
+	}})
+
+	dir := t.TempDir()
+	var files []string
+	for i, rows := range exports {
+		file := filepath.Join(dir, string(rune('a'+i))+".csv")
+		if err := os.WriteFile(file, []byte("Booked On,Charged,Details\n"+rows), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestLoadCSVExportsOfOneMonth(t *testing.T) {
+	tests := []struct {
+		name     string
+		exports  []string
+		rows     int
+		total    string
+		overlaps int
+	}{
+		{
+			name:    "split month",
+			exports: []string{"2025-03-02,100,COFFEE\n2025-03-05,50,BUS\n", "2025-03-20,250,BOOKS\n"},
+			rows:    3, total: "400.00",
+		},
+		{
+			name:    "overlapping exports",
+			exports: []string{"2025-03-02,100,COFFEE\n2025-03-05,50,BUS\n", "2025-03-05,50,BUS\n2025-03-20,250,BOOKS\n"},
+			rows:    3, total: "400.00",
+		},
+		{
+			name:    "repeated purchase",
+			exports: []string{"2025-03-05,50,BUS\n", "2025-03-05,50,BUS\n2025-03-05,50,BUS\n"},
+			rows:    2, total: "100.00",
+		},
+		{
+			name:    "same export twice",
+			exports: []string{"2025-03-02,100,COFFEE\n", "2025-03-02,100,COFFEE\n"},
+			rows:    1, total: "100.00", overlaps: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, report, err := LoadStatementFiles(writeCSVExports(t, tt.exports...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(statements))
+			}
+			stmt := statements[0]
+			if len(stmt.Transactions) != tt.rows || stmt.CurTotAmt != tt.total {
+				t.Errorf("got %d rows totalling %s, want %d totalling %s", len(stmt.Transactions), stmt.CurTotAmt, tt.rows, tt.total)
+			}
+			if len(report.Overlaps) != tt.overlaps || len(report.Conflicts) != 0 {
+				t.Errorf("report = %+v", report)
+			}
+			if tt.overlaps == 0 && !strings.Contains(stmt.Source, ", ") {
+				t.Errorf("source %q does not name both files", stmt.Source)
+			}
+		})
+	}
+}
+
+func TestCSVProfileDateFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		ok     bool
+	}{
+		{"", true},
+		{"DD/MM/YYYY", true},
+		{"YYYY-MM-DD", true},
+		{"YYMMDD", true},
+		{"MM.DD.YY", true},
+		{"2006-01-02", false},
+		{"DD/Mon/YYYY", false},
+		{"DD/MM", false},
+		{"YYYY-MM-DD hh:mm", false},
+		{"DD/MM/YYYYY", false},
+		{"DD/MM/DD/YYYY", false},
+	}
+	for _, tt := range tests {
+		p := CSVProfile{DateColumn: "Date", AmountColumn: "Amount", DescriptionColumn: "Details", DateFormat: tt.format}
+		if err := p.validate(); (err == nil) != tt.ok {
+			t.Errorf("validate(%q) = %v, want ok %v", tt.format, err, tt.ok)
+		}
+	}
+}
+
+func TestCSVErrorLineOfMultilineRow(t *testing.T) {
+	parser := csvParser{profile: CSVProfile{DateColumn: "Date", AmountColumn: "Amount", DescriptionColumn: "Details"}}
+	data := "Date,Amount,Details\n2025-03-02,100,\"COFFEE\nAND CAKE\"\n2025-13-01,50,BUS\n"
+	_, err := parser.Parse(strings.NewReader(data))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Errorf("Parse() error = %v, want it on line 4", err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minguoOffset converts a Republic of China (Minguo) year to a Gregorian one
+const minguoOffset = 1911
+
+// displayDateLayout is used wherever a date is shown to the user
+const displayDateLayout = "2006/01/02"
+
+// ParseDate parses the date formats found in statement exports:
+// ISO (2025-03-14), slashes (2025/03/14), compact (20250314), Minguo years
+// (114/03/14, 1140314) and month/day without a year (03/14), which takes
+// refYear. An empty string returns the zero time without an error.
+func ParseDate(s string, refYear int) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	// Drop a time of day, e.g. 2025-03-14T10:00:00 or 2025/03/14 10:00
+	if i := strings.IndexAny(s, "T "); i > 0 {
+		s = s[:i]
+	}
+
+	var year, month, day int
+	var err error
+
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' || r == '.' })
+	switch {
+	case len(parts) == 3:
+		year, err = parseYear(parts[0])
+		if err == nil {
+			month, err = parseDigits(parts[1])
+		}
+		if err == nil {
+			day, err = parseDigits(parts[2])
+		}
+
+	case len(parts) == 2 && refYear > 0:
+		year = refYear
+		month, err = parseDigits(parts[0])
+		if err == nil {
+			day, err = parseDigits(parts[1])
+		}
+
+	case len(parts) == 1 && (len(s) == 8 || len(s) == 7):
+		// Compact YYYYMMDD or Minguo YYYMMDD
+		split := len(s) - 4
+		year, err = parseYear(s[:split])
+		if err == nil {
+			month, err = parseDigits(s[split : split+2])
+		}
+		if err == nil {
+			day, err = parseDigits(s[split+2:])
+		}
+
+	default:
+		err = fmt.Errorf("unrecognized date format")
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %v", s, err)
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// parseYear parses a four digit Gregorian year or a two/three digit Minguo
+// year. A four digit year below 1000, such as 0114, could be either and is
+// rejected, as is Minguo year 0.
+func parseYear(s string) (int, error) {
+	year, err := parseDigits(s)
+	switch {
+	case err != nil:
+		return 0, err
+	case len(s) < 2 || len(s) > 4:
+		return 0, fmt.Errorf("year %q is neither Gregorian nor Minguo", s)
+	case len(s) == 4 && year < 1000:
+		return 0, fmt.Errorf("ambiguous year %q", s)
+	case len(s) == 4:
+		return year, nil
+	case year == 0:
+		return 0, fmt.Errorf("invalid Minguo year %q", s)
+	}
+	return year + minguoOffset, nil
+}
+
+// parseDigits parses an unsigned decimal number, rejecting signs and spaces
+// that strconv.Atoi would accept or trim
+func parseDigits(s string) (int, error) {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// formatDate renders a parsed date, falling back to the raw value when the
+// date could not be parsed
+func formatDate(t time.Time, raw string) string {
+	if t.IsZero() {
+		return raw
+	}
+	return t.Format(displayDateLayout)
+}
+
+// DateRange is an inclusive range of days; a zero bound is open
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDateRange parses the bounds of a range, either of which may be empty
+func ParseDateRange(from, to string) (DateRange, error) {
+	var r DateRange
+	var err error
+	if r.From, err = ParseDate(from, 0); err != nil {
+		return r, err
+	}
+	if r.To, err = ParseDate(to, 0); err != nil {
+		return r, err
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return r, fmt.Errorf("range ends before it starts")
+	}
+	return r, nil
+}
+
+// IsZero reports whether the range is unbounded on both sides
+func (r DateRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains reports whether t falls inside the range. Undated values only
+// match an unbounded range.
+func (r DateRange) Contains(t time.Time) bool {
+	if r.IsZero() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// FilterByDate keeps the transactions dated inside the range, dropping
+// statements that are left without transactions
+func FilterByDate(statements []Statement, r DateRange) []Statement {
+	if r.IsZero() {
+		return statements
+	}
+
+	var filtered []Statement
+	for _, stmt := range statements {
+		var txs []Transaction
+		for _, tx := range stmt.Transactions {
+			if r.Contains(tx.TransactionDate) {
+				txs = append(txs, tx)
+			}
+		}
+		if len(txs) > 0 {
+			stmt.Transactions = txs
+			filtered = append(filtered, stmt)
+		}
+	}
+	return filtered
+}
+
+// DayTotal is the billed spend of a single day
+type DayTotal struct {
+	Date   time.Time `json:"date"`
+	Count  int       `json:"count"`
+	Totals Totals    `json:"totals"`
+}
+
+// DailyTotals aggregates billed amounts per transaction date, oldest first.
+// Undated transactions are left out.
+func DailyTotals(txs []Transaction) []DayTotal {
+	byDay := make(map[time.Time]*DayTotal)
+	for _, tx := range txs {
+		if tx.TransactionDate.IsZero() {
+			continue
+		}
+		day, ok := byDay[tx.TransactionDate]
+		if !ok {
+			day = &DayTotal{Date: tx.TransactionDate, Totals: Totals{}}
+			byDay[tx.TransactionDate] = day
+		}
+		day.Count++
+		day.Totals.Add(tx.BilledAmount)
+	}
+
+	days := make([]DayTotal, 0, len(byDay))
+	for _, day := range byDay {
+		days = append(days, *day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+	return days
+}
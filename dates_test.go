@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		refYear int
+		want    string // Empty for the zero time
+		wantErr bool
+	}{
+		{"2025-03-14", 0, "2025-03-14", false},
+		{"2025/03/14", 0, "2025-03-14", false},
+		{"2025.3.4", 0, "2025-03-04", false},
+		{"20250314", 0, "2025-03-14", false},
+		{"2025-03-14T10:00:00", 0, "2025-03-14", false},
+		{"2025/03/14 10:00", 0, "2025-03-14", false},
+		{" 2025-03-14 ", 0, "2025-03-14", false},
+		{"", 0, "", false},
+
+		// Minguo years have two or three digits
+		{"114/03/14", 0, "2025-03-14", false},
+		{"1140314", 0, "2025-03-14", false},
+		{"99/12/31", 0, "2010-12-31", false},
+		{"099/12/31", 0, "2010-12-31", false},
+		{"113/02/29", 0, "2024-02-29", false},
+
+		// Month and day take the reference year
+		{"03/14", 2025, "2025-03-14", false},
+		{"02/29", 2024, "2024-02-29", false},
+		{"03/14", 0, "", true},
+
+		// Ambiguous or malformed years
+		{"0114/03/14", 0, "", true},
+		{"00140314", 0, "", true},
+		{"000/03/14", 0, "", true},
+		{"5/03/14", 0, "", true},
+		{"20251/03/14", 0, "", true},
+		{"+114/03/14", 0, "", true},
+
+		// Invalid dates
+		{"2025-02-29", 0, "", true},
+		{"114/02/29", 0, "", true},
+		{"2025-13-01", 0, "", true},
+		{"2025-04-31", 0, "", true},
+		{"2025-00-10", 0, "", true},
+		{"2025-03-00", 0, "", true},
+		{"2025/+3/14", 0, "", true},
+		{"2025-03", 0, "", true},
+		{"202503140", 0, "", true},
+		{"2025031", 0, "", true},
+		{"14 March 2025", 0, "", true},
+		{"yesterday", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDate(tt.in, tt.refYear)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate(%q, %d) error = %v, want error %v", tt.in, tt.refYear, err, tt.wantErr)
+			}
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("ParseDate(%q, %d) = %s, want the zero time", tt.in, tt.refYear, got)
+				}
+				return
+			}
+			if !got.Equal(day(tt.want)) {
+				t.Errorf("ParseDate(%q, %d) = %s, want %s", tt.in, tt.refYear, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  bool
+		inside   []string
+		outside  []string
+	}{
+		{"", "", false, []string{"1999-01-01", "2030-12-31"}, nil},
+		{"2025-03-01", "2025-03-31", false, []string{"2025-03-01", "2025-03-31"}, []string{"2025-02-28", "2025-04-01"}},
+		{"114/03/01", "", false, []string{"2025-03-01", "2030-01-01"}, []string{"2025-02-28"}},
+		{"", "20250331", false, []string{"2000-01-01", "2025-03-31"}, []string{"2025-04-01"}},
+		{"2025-03-14", "2025-03-14", false, []string{"2025-03-14"}, []string{"2025-03-13", "2025-03-15"}},
+		{"2025-04-01", "2025-03-01", true, nil, nil},
+		{"2025-02-30", "", true, nil, nil},
+		{"", "0114/03/31", true, nil, nil},
+		{"03/01", "", true, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+".."+tt.to, func(t *testing.T) {
+			r, err := ParseDateRange(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDateRange(%q, %q) error = %v, want error %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			for _, d := range tt.inside {
+				if !r.Contains(day(d)) {
+					t.Errorf("range excludes %s", d)
+				}
+			}
+			for _, d := range tt.outside {
+				if r.Contains(day(d)) {
+					t.Errorf("range includes %s", d)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByDate(t *testing.T) {
+	statements := journalStatements()
+	statements[1].Transactions = append(statements[1].Transactions, journalTx("UNDATED", 1, "", ""))
+
+	r, err := ParseDateRange("2025-03-01", "2025-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, stmt := range FilterByDate(statements, r) {
+		for _, tx := range stmt.Transactions {
+			got = append(got, tx.Description)
+		}
+	}
+	if strings.Join(got, ",") != "LATE LUNCH,BREAKFAST" {
+		t.Errorf("FilterByDate() kept %q", got)
+	}
+}
+
+func TestNormalizeStatementsReportsInvalidDates(t *testing.T) {
+	statements := []Statement{
+		{
+			StmtYr: "114", StmtMo: "03", StmtDate: "114/03/25", PmtDue: "114/02/30", Currency: "TWD",
+			Transactions: []Transaction{
+				{Description: "MINGUO", TxnDate: "114/03/14", NtdAmount: "100"},
+				{Description: "YEARLESS", TxnDate: "03/20", NtdAmount: "100"},
+				{Description: "AMBIGUOUS", TxnDate: "0114/03/14", NtdAmount: "100"},
+				{Description: "NO SUCH DAY", TxnDate: "2025-02-29", NtdAmount: "100"},
+			},
+		},
+		{
+			// Without a statement date or period a yearless date has no year
+			StmtYr: "20x5", StmtMo: "03", Currency: "TWD",
+			Transactions: []Transaction{{Description: "ORPHAN", TxnDate: "03/20", NtdAmount: "100"}},
+		},
+	}
+	issues := normalizeStatements(statements)
+
+	txs := statements[0].Transactions
+	if !txs[0].TransactionDate.Equal(day("2025-03-14")) || !txs[1].TransactionDate.Equal(day("2025-03-20")) {
+		t.Errorf("valid dates parsed as %s and %s", txs[0].TransactionDate, txs[1].TransactionDate)
+	}
+	if !statements[0].PaymentDue.IsZero() || !txs[2].TransactionDate.IsZero() || !txs[3].TransactionDate.IsZero() {
+		t.Error("invalid dates were given a value")
+	}
+	if !statements[1].Transactions[0].TransactionDate.IsZero() {
+		t.Errorf("yearless date without a statement date = %s", statements[1].Transactions[0].TransactionDate)
+	}
+
+	for _, want := range []string{"pmtDue", "(AMBIGUOUS): txnDate", "(NO SUCH DAY): txnDate", "invalid statement period", "(ORPHAN): txnDate"} {
+		found := false
+		for _, issue := range issues {
+			found = found || strings.Contains(issue, want)
+		}
+		if !found {
+			t.Errorf("no issue for %s in %q", want, issues)
+		}
+	}
+	if len(issues) != 5 {
+		t.Errorf("got %d issues, want 5: %q", len(issues), issues)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation traces how a transaction got its category: the cleaning
+// steps applied to its description, the rule that matched, the classifier
+// suggestion and any manual override
+type Explanation struct {
+	Description string      // As it appears on the statement
+	Steps       []CleanStep // Normalized description followed by each cleaning step that changed it
+
+	Rule        *Rule  // First matching rule, nil when none matched
+	RuleIndex   int    // Position of Rule in the categorizer, from 0
+	UserRule    bool   // Rule came from the user's rules file
+	MatchedStep int    // First entry of Steps the rule matches; -1 for rules on other fields
+	Value       string // Value the rule was tested against
+	Span        [2]int // Part of Value the rule matched
+
+	Suggestion string  // Classifier suggestion when no rule matched
+	Confidence float64 // Classifier confidence in Suggestion
+	Confident  bool    // Confidence reached the categorizer's threshold
+	Override   string  // Category set by hand, if any
+
+	Category string // Resulting category
+}
+
+// Explain categorizes a copy of tx and records every decision on the way.
+// overrides may be nil.
+func (c *Categorizer) Explain(tx Transaction, overrides *OverrideStore) Explanation {
+	if tx.NormalizedDescription == "" {
+		tx.NormalizedDescription = ToCDB(tx.Description)
+	}
+
+	e := Explanation{
+		Description: tx.Description,
+		Steps:       append([]CleanStep{{Name: "original", Result: tx.Description}}, CleanDescriptionSteps(tx.NormalizedDescription)...),
+		MatchedStep: -1,
+		Category:    CategoryOther,
+	}
+	// Only keep the half-width step when it changed something
+	if e.Steps[1].Result == e.Steps[0].Result {
+		e.Steps = append(e.Steps[:1], e.Steps[2:]...)
+	} else {
+		e.Steps[1].Name = "half-width"
+	}
+	cleanDesc := e.Steps[len(e.Steps)-1].Result
+
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		value, span, ok := rule.match(&tx, cleanDesc)
+		if !ok {
+			continue
+		}
+
+		e.Rule = rule
+		e.RuleIndex = i
+		e.UserRule = i < c.UserRules
+		e.Value = value
+		e.Span = span
+		e.Category = rule.Category
+
+		// Find the earliest cleaning step after which the rule already matched
+		if rule.Field == FieldDescription {
+			for j, step := range e.Steps {
+				if value, span, ok := rule.match(&tx, step.Result); ok {
+					e.MatchedStep = j
+					e.Value = value
+					e.Span = span
+					break
+				}
+			}
+		}
+		break
+	}
+
+	if e.Rule == nil && c.Classifier != nil {
+		e.Suggestion, e.Confidence = c.Classifier.Predict(&tx, cleanDesc)
+		e.Confident = e.Suggestion != "" && e.Confidence >= c.MinConfidence
+		if e.Confident {
+			e.Category = e.Suggestion
+		}
+	}
+
+	if overrides != nil {
+		if o, ok := overrides.Get(tx); ok && o.Category != "" {
+			e.Override = o.Category
+			e.Category = o.Category
+		}
+	}
+
+	return e
+}
+
+// Lines renders the explanation as text, one fact per line
+func (e Explanation) Lines() []string {
+	var lines []string
+	lines = append(lines, "Cleaning:")
+	for i, step := range e.Steps {
+		lines = append(lines, fmt.Sprintf("  %d. %-17s %s", i+1, step.Name, step.Result))
+	}
+
+	if e.Rule == nil {
+		lines = append(lines, "Rule:     no rule matched")
+	} else {
+		source := "built-in"
+		if e.UserRule {
+			source = "user"
+		}
+		lines = append(lines, fmt.Sprintf("Rule:     #%d (%s) %s", e.RuleIndex+1, source, e.Rule))
+
+		matched := e.Value[:e.Span[0]] + "[" + e.Value[e.Span[0]:e.Span[1]] + "]" + e.Value[e.Span[1]:]
+		if e.MatchedStep >= 0 {
+			lines = append(lines, fmt.Sprintf("Matched:  %s after step %d (%s)", matched, e.MatchedStep+1, e.Steps[e.MatchedStep].Name))
+		} else {
+			lines = append(lines, fmt.Sprintf("Matched:  %s (%s)", matched, e.Rule.Field))
+		}
+	}
+
+	switch {
+	case e.Rule != nil:
+	case e.Suggestion == "":
+		lines = append(lines, "Model:    no suggestion")
+	case e.Confident:
+		lines = append(lines, fmt.Sprintf("Model:    %s (%.0f%% confidence)", e.Suggestion, e.Confidence*100))
+	default:
+		lines = append(lines, fmt.Sprintf("Model:    %s (%.0f%% confidence, below threshold)", e.Suggestion, e.Confidence*100))
+	}
+
+	if e.Override != "" {
+		lines = append(lines, "Override: "+e.Override)
+	}
+	lines = append(lines, "Category: "+e.Category)
+
+	return lines
+}
+
+// String renders the explanation as text
+func (e Explanation) String() string {
+	return strings.Join(e.Lines(), "\n")
+}
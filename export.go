@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// exportColumns are the CSV header, one column per TransactionRow field
+var exportColumns = []string{
+	"statement", "date", "category", "billed_amount", "billed_currency",
+	"original_amount", "original_currency", "description", "location",
+	"card", "apple_pay_card", "merchant", "tags", "note", "foreign", "foreign_fees",
+}
+
+// ExportCSV writes rows as CSV with ISO dates and plain decimal amounts
+func ExportCSV(w io.Writer, rows []TransactionRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write(exportColumns)
+	for _, row := range rows {
+		date := ""
+		if !row.Date.IsZero() {
+			date = row.Date.Format("2006-01-02")
+		}
+		cw.Write([]string{
+			row.Period,
+			date,
+			row.Category,
+			row.Billed.String(),
+			row.Billed.Currency,
+			row.Original.String(),
+			row.Original.Currency,
+			row.Description,
+			row.Location,
+			row.Card,
+			row.ApplePayCard,
+			row.Merchant,
+			strings.Join(row.Tags, " "),
+			row.Note,
+			strconv.FormatBool(row.Foreign),
+			strconv.Itoa(row.ForeignFees),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportJSON writes rows as an indented JSON array
+func ExportJSON(w io.Writer, rows []TransactionRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(rows)
+}
+
+// exportFormat picks the format for an output file from its extension,
+// unless one is given
+func exportFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "" {
+			format = "csv"
+		}
+	}
+	switch format {
+	case "bean":
+		return "beancount", nil
+	case "hledger", "journal":
+		return "ledger", nil
+	case "csv", "json", "beancount", "ledger", "xlsx":
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown export format %q", format)
+}
+
+// ExportFile writes rows to a file, or stdout for "-", in the given format
+// or the one its extension names
+func ExportFile(path, format string, rows []TransactionRow) error {
+	format, err := exportFormat(format, path)
+	if err != nil {
+		return err
+	}
+
+	write := ExportCSV
+	switch format {
+	case "json":
+		write = ExportJSON
+	case "beancount", "ledger", "xlsx":
+		return fmt.Errorf("%s exports hold whole statements; use the export command", format)
+	}
+	return createOutput(path, func(w io.Writer) error { return write(w, rows) })
+}
+
+// createOutput calls write with a new file, or stdout for "-"
+func createOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
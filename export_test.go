@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sampleRows loads the HSBC sample statement and selects its rows
+func sampleRows(t *testing.T, selection rowFlags) []TransactionRow {
+	t.Helper()
+	t.Setenv("STATEMENTS_CONFIG_DIR", t.TempDir())
+	s, err := loadSession(options{}, []string{filepath.Join("testdata", "hsbc", "2025-03.json")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := selection.rows(s.statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestExportCSVWithFilter(t *testing.T) {
+	rows := sampleRows(t, rowFlags{filter: "-cat:Other amount>100", sort: "amount"})
+	var out strings.Builder
+	if err := ExportCSV(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `statement,date,category,billed_amount,billed_currency,original_amount,original_currency,description,location,card,apple_pay_card,merchant,tags,note,foreign,foreign_fees
+2025/03,2025-03-12,Shopping,1290.00,TWD,1290.00,TWD,UNIQLO TAIPEI 101,,1234,,UNIQLO TAIPEI,,,false,0
+2025/03,2025-03-08,Transport,438.00,TWD,12.50,EUR,UBER TRIP,MILANO,1234,,UBER,,,true,0
+2025/03,2025-03-02,Food,150.00,TWD,150.00,TWD,STARBUCKS TAIPEI,,1234,1234,STARBUCKS TAIPEI,,,false,0
+`
+	if out.String() != want {
+		t.Errorf("ExportCSV() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestExportJSONWithFilter(t *testing.T) {
+	rows := sampleRows(t, rowFlags{filter: "uniqlo", sort: "date"})
+	var out strings.Builder
+	if err := ExportJSON(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []struct {
+		Statement string `json:"statement"`
+		Date      string `json:"date"`
+		Billed    struct {
+			Amount   json.Number `json:"amount"`
+			Currency string      `json:"currency"`
+		} `json:"billed"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("ExportJSON() wrote invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("ExportJSON() wrote %d rows, want the purchase and the refund:\n%s", len(got), out.String())
+	}
+	for i, want := range []string{"2025-03-12T00:00:00Z 1290.00 TWD", "2025-03-15T00:00:00Z -290.00 TWD"} {
+		row := got[i]
+		if s := row.Date + " " + row.Billed.Amount.String() + " " + row.Billed.Currency; s != want || row.Description != "UNIQLO TAIPEI 101" || row.Statement != "2025/03" {
+			t.Errorf("row %d = %+v, want %s", i, row, want)
+		}
+	}
+
+	// An empty selection is an empty array rather than null
+	out.Reset()
+	if err := ExportJSON(&out, sampleRows(t, rowFlags{filter: "nothing-matches", sort: "date"})); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("ExportJSON() of no rows = %q, %v", out.String(), err)
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		format, path, want string
+	}{
+		{"", "out.csv", "csv"},
+		{"", "out.JSON", "json"},
+		{"", "-", "csv"},
+		{"", "cards.bean", "beancount"},
+		{"", "cards.journal", "ledger"},
+		{"hledger", "cards.txt", "ledger"},
+		{"json", "out.csv", "json"},
+		{"", "book.xlsx", "xlsx"},
+		{"", "out.txt", ""},
+	}
+	for _, tt := range tests {
+		got, err := exportFormat(tt.format, tt.path)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("exportFormat(%q, %q) = %q, %v, want %q", tt.format, tt.path, got, err, tt.want)
+		}
+	}
+}
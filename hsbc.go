@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+func init() {
+	RegisterParser(hsbcJSONParser{}, priorityFormat)
+}
+
+// hsbcJSONParser reads the statementlist.json array served by HSBC Taiwan
+type hsbcJSONParser struct{}
+
+func (hsbcJSONParser) Name() string {
+	return "HSBC Taiwan JSON"
+}
+
+// Detect looks for a JSON array whose first element carries statement fields
+func (hsbcJSONParser) Detect(r io.Reader) bool {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('[') {
+		return false
+	}
+
+	// An empty list is still a valid (if useless) export
+	if !dec.More() {
+		return true
+	}
+
+	var first map[string]json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return false
+	}
+	_, hasYear := first["stmtYr"]
+	_, hasTxs := first["transactions"]
+	return hasYear && hasTxs
+}
+
+func (hsbcJSONParser) Parse(r io.Reader) ([]Statement, error) {
+	var statements []Statement
+	if err := json.NewDecoder(r).Decode(&statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal is the double-entry form of a set of statements, ready to be
+// written out as a plain text accounting file
+type Journal struct {
+	Start      time.Time          // Day every account is opened
+	Accounts   []string           // Every account used, sorted
+	Opening    []JournalPad       // Opening balances of the liability accounts
+	Statements []JournalStatement // In date order
+}
+
+// JournalPad brings a liability account to the previous balance of the
+// first statement that uses it
+type JournalPad struct {
+	Account string
+	From    string // Equity account the balance comes from
+	Balance Money  // Negative when owing
+}
+
+// JournalStatement holds the transactions of one statement and the balance
+// its total asserts
+type JournalStatement struct {
+	Period       string // Year and month, e.g. 2025/03
+	Transactions []JournalTransaction
+
+	// The balance assertion, skipped when BalanceDate is zero
+	BalanceDate time.Time // Day after the statement date
+	Account     string    // Liability account of the statement
+	Balance     Money     // Negative when owing
+	SkipReason  string    // Why there is no balance assertion, if there could be one
+}
+
+// JournalTransaction is one transaction with its postings
+type JournalTransaction struct {
+	Date        time.Time // Day the charge is booked, see journalDate
+	Transacted  time.Time // Transaction date, zero when unknown
+	Posted      time.Time // Posting date, zero when unknown
+	Payee       string
+	Narration   string
+	Tags        []string
+	Meta        []JournalMeta
+	Postings    []JournalPosting
+	Fingerprint string
+}
+
+// JournalMeta is a metadata key and value of a transaction
+type JournalMeta struct {
+	Key, Value string
+}
+
+// JournalPosting is one leg of a transaction
+type JournalPosting struct {
+	Account string
+	Amount  Money
+	Price   Money // Total price in the billed currency when Amount is foreign, zero otherwise
+}
+
+// BuildJournal turns statements into journal transactions: purchases and
+// refunds between a card's liability account and their category's expense
+// account, foreign fees against the fee account and online bank payments
+// against the payments account. Each statement with a statement date
+// asserts its total as the card balance on the next day.
+func BuildJournal(statements []Statement, accounts Accounts) (Journal, error) {
+	var j Journal
+	used := make(map[string]bool)
+	padded := make(map[string]bool)
+
+	for _, stmt := range sortedStatements(statements) {
+		js := JournalStatement{Period: fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo)}
+		cards := make(map[string]bool)
+
+		txs := append([]Transaction(nil), stmt.Transactions...)
+		sort.SliceStable(txs, func(a, b int) bool { return journalDate(stmt, txs[a]).Before(journalDate(stmt, txs[b])) })
+		for _, tx := range txs {
+			jt, err := journalTransaction(stmt, tx, accounts)
+			if err != nil {
+				return j, fmt.Errorf("statement %s: %w", js.Period, err)
+			}
+			for _, p := range jt.Postings {
+				used[p.Account] = true
+			}
+			cards[accounts.Card(tx.CardNo)] = true
+			if j.Start.IsZero() || jt.Date.Before(j.Start) {
+				j.Start = jt.Date
+			}
+			js.Transactions = append(js.Transactions, jt)
+		}
+
+		if !stmt.StatementDate.IsZero() {
+			if len(cards) == 0 {
+				cards[accounts.DefaultCard] = true
+			}
+			if len(cards) > 1 {
+				js.SkipReason = "the statement spans " + strings.Join(sortedKeys(cards), ", ")
+			} else {
+				js.BalanceDate = stmt.StatementDate.AddDate(0, 0, 1)
+				js.Account = sortedKeys(cards)[0]
+				js.Balance = stmt.Total.Neg()
+				used[js.Account] = true
+
+				if !padded[js.Account] && !stmt.PreviousBalance.IsZero() {
+					j.Opening = append(j.Opening, JournalPad{js.Account, accounts.OpeningBalances, stmt.PreviousBalance.Neg()})
+					used[accounts.OpeningBalances] = true
+				}
+				padded[js.Account] = true
+			}
+			if j.Start.IsZero() || stmt.StatementDate.Before(j.Start) {
+				j.Start = stmt.StatementDate
+			}
+		}
+
+		j.Statements = append(j.Statements, js)
+	}
+
+	j.Accounts = sortedKeys(used)
+	return j, nil
+}
+
+// journalTransaction builds the postings of a single transaction
+func journalTransaction(stmt Statement, tx Transaction, accounts Accounts) (JournalTransaction, error) {
+	jt := JournalTransaction{
+		Date:        journalDate(stmt, tx),
+		Transacted:  tx.TransactionDate,
+		Posted:      tx.PostedDate,
+		Payee:       tx.Merchant,
+		Narration:   GetCleanDescription(tx.NormalizedDescription),
+		Tags:        tx.Tags,
+		Fingerprint: Fingerprint(tx),
+	}
+	if jt.Payee == jt.Narration {
+		jt.Payee = ""
+	}
+	if jt.Date.IsZero() {
+		return jt, fmt.Errorf("no date for %q", tx.Description)
+	}
+	for _, m := range []JournalMeta{{"location", tx.TxnLoc}, {"card", tx.CardNo}, {"note", tx.Note}} {
+		if m.Value != "" {
+			jt.Meta = append(jt.Meta, m)
+		}
+	}
+
+	card := accounts.Card(tx.CardNo)
+	other := JournalPosting{Account: accounts.Category(tx.Category), Amount: tx.BilledAmount}
+	switch {
+	case strings.HasPrefix(ToCDB(tx.Description), "國外交易手續費"):
+		other.Account = accounts.ForeignFees
+	case tx.NormalizedDescription == "網路銀行繳款":
+		other.Account = accounts.Payments
+	case tx.OriginalAmount.Currency != tx.BilledAmount.Currency && !tx.OriginalAmount.IsZero():
+		other.Amount, other.Price = tx.OriginalAmount, tx.BilledAmount.Abs()
+	}
+	jt.Postings = []JournalPosting{other, {Account: card, Amount: tx.BilledAmount.Neg()}}
+	return jt, nil
+}
+
+// journalDate is the day a transaction is booked: its posting date, or
+// failing that the statement's date or its transaction date. Purchases
+// made before a statement closes can post on the next one, so booking by
+// transaction date would put them before the earlier statement's balance
+// assertion. Nothing is booked after its own statement's date.
+func journalDate(stmt Statement, tx Transaction) time.Time {
+	for _, t := range []time.Time{tx.PostedDate, stmt.StatementDate, tx.TransactionDate, stmt.Period} {
+		if !t.IsZero() {
+			if !stmt.StatementDate.IsZero() && t.After(stmt.StatementDate) {
+				return stmt.StatementDate
+			}
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// sortedStatements returns the statements ordered by period, then
+// statement date
+func sortedStatements(statements []Statement) []Statement {
+	sorted := append([]Statement(nil), statements...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Period.Equal(sorted[j].Period) {
+			return sorted[i].Period.Before(sorted[j].Period)
+		}
+		return sorted[i].StatementDate.Before(sorted[j].StatementDate)
+	})
+	return sorted
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
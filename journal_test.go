@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// day is a date at midnight UTC
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// twd is an amount in whole Taiwan dollars
+func twd(n int64) Money {
+	return Money{Units: n * moneyScale, Currency: "TWD"}
+}
+
+// journalTx is a TWD purchase on card 1234
+func journalTx(desc string, amount int64, transacted, posted string) Transaction {
+	tx := Transaction{
+		Description:           desc,
+		NormalizedDescription: desc,
+		CardNo:                "1234",
+		Category:              CategoryFood,
+		BilledAmount:          twd(amount),
+		OriginalAmount:        twd(amount),
+		TxnDate:               transacted,
+	}
+	if transacted != "" {
+		tx.TransactionDate = day(transacted)
+	}
+	if posted != "" {
+		tx.PostedDate = day(posted)
+	}
+	return tx
+}
+
+// journalStatements are two billing cycles closing on the 25th, the second
+// with a purchase made before the first closed but posted after it
+func journalStatements() []Statement {
+	return []Statement{
+		{
+			StmtYr: "2025", StmtMo: "04", Currency: "TWD",
+			Period: day("2025-04-01"), StatementDate: day("2025-04-25"),
+			PreviousBalance: twd(1000), Total: twd(1500),
+			Transactions: []Transaction{
+				journalTx("LATE LUNCH", 300, "2025-03-24", "2025-03-27"),
+				journalTx("DINNER", 200, "2025-04-10", "2025-04-11"),
+			},
+		},
+		{
+			StmtYr: "2025", StmtMo: "03", Currency: "TWD",
+			Period: day("2025-03-01"), StatementDate: day("2025-03-25"),
+			PreviousBalance: twd(0), Total: twd(1000),
+			Transactions: []Transaction{
+				journalTx("BREAKFAST", 1000, "2025-03-02", "2025-03-03"),
+			},
+		},
+	}
+}
+
+func TestBuildJournalBalanceAssertionsHold(t *testing.T) {
+	j, err := BuildJournal(journalStatements(), defaultAccounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Statements) != 2 || j.Statements[0].Period != "2025/03" {
+		t.Fatalf("statements not in date order: %+v", j.Statements)
+	}
+
+	// Replay every posting dated before each assertion, as Beancount does
+	for _, stmt := range j.Statements {
+		if stmt.BalanceDate.IsZero() {
+			t.Fatalf("statement %s has no balance assertion", stmt.Period)
+		}
+		balance := Money{Currency: "TWD"}
+		for _, pad := range j.Opening {
+			if pad.Account == stmt.Account {
+				balance = balance.Add(pad.Balance)
+			}
+		}
+		for _, other := range j.Statements {
+			for _, tx := range other.Transactions {
+				if !tx.Date.Before(stmt.BalanceDate) {
+					continue
+				}
+				for _, p := range tx.Postings {
+					if p.Account == stmt.Account {
+						balance = balance.Add(p.Amount)
+					}
+				}
+			}
+		}
+		if balance.Cmp(stmt.Balance) != 0 {
+			t.Errorf("%s balance on %s = %s, asserted %s", stmt.Period, stmt.BalanceDate.Format("2006-01-02"), balance, stmt.Balance)
+		}
+	}
+}
+
+func TestBuildJournalBooksByPostingDate(t *testing.T) {
+	j, err := BuildJournal(journalStatements(), defaultAccounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	late := j.Statements[1].Transactions[0]
+	if late.Narration != "LATE LUNCH" {
+		t.Fatalf("first April entry = %q", late.Narration)
+	}
+	if !late.Date.Equal(day("2025-03-27")) || !late.Transacted.Equal(day("2025-03-24")) {
+		t.Errorf("booked %s, transacted %s; want 2025-03-27 and 2025-03-24", late.Date, late.Transacted)
+	}
+
+	var b strings.Builder
+	if err := WriteBeancount(&b, j); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "transaction-date: 2025-03-24") {
+		t.Errorf("transaction date missing from Beancount output:\n%s", b.String())
+	}
+}
+
+func TestJournalDate(t *testing.T) {
+	stmt := Statement{Period: day("2025-03-01"), StatementDate: day("2025-03-25")}
+	tests := []struct {
+		name   string
+		tx     Transaction
+		booked string
+	}{
+		{"posting date", journalTx("A", 1, "2025-03-01", "2025-03-02"), "2025-03-02"},
+		{"no posting date", journalTx("A", 1, "2025-03-01", ""), "2025-03-25"},
+		{"posted after the statement", journalTx("A", 1, "2025-03-20", "2025-03-28"), "2025-03-25"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := journalDate(stmt, tt.tx); !got.Equal(day(tt.booked)) {
+				t.Errorf("journalDate() = %s, want %s", got.Format("2006-01-02"), tt.booked)
+			}
+		})
+	}
+
+	undated := Statement{}
+	if got := journalDate(undated, journalTx("A", 1, "2025-03-01", "")); !got.Equal(day("2025-03-01")) {
+		t.Errorf("journalDate() without statement dates = %s, want the transaction date", got)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ledgerDate is the date layout of Ledger and hledger transactions
+const ledgerDate = "2006/01/02"
+
+// ledgerIDPattern finds the entry IDs of an earlier export
+var ledgerIDPattern = regexp.MustCompile(`(?m)^\s+; Fingerprint: (\S+)\s*$`)
+
+// ledgerAccountPattern finds the account declarations of an earlier export
+var ledgerAccountPattern = regexp.MustCompile(`(?m)^account (.+?)\s*$`)
+
+// ledgerEntryPattern finds the date that starts an entry
+var ledgerEntryPattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2})\s`)
+
+// LedgerFile holds what an earlier export already wrote to a journal file
+type LedgerFile struct {
+	IDs      map[string]bool      // Fingerprint tags of the entries
+	Accounts map[string]bool      // Declared accounts
+	Last     map[string]time.Time // Date of the last entry posting to each account
+}
+
+// ReadLedgerFile collects the entry IDs and accounts of a journal file. A
+// missing file is empty.
+func ReadLedgerFile(path string) (LedgerFile, error) {
+	f := LedgerFile{IDs: make(map[string]bool), Accounts: make(map[string]bool), Last: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	for _, m := range ledgerIDPattern.FindAllSubmatch(data, -1) {
+		f.IDs[string(m[1])] = true
+	}
+	for _, m := range ledgerAccountPattern.FindAllSubmatch(data, -1) {
+		f.Accounts[string(m[1])] = true
+	}
+
+	// Postings are indented lines below the entry date, with the account
+	// before two spaces or a tab and an optional comment after it
+	var date time.Time
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := ledgerEntryPattern.FindStringSubmatch(line); m != nil {
+			date, _ = time.Parse(ledgerDate, m[1])
+			continue
+		}
+		posting := strings.TrimSpace(line)
+		if date.IsZero() || posting == "" || posting == line || strings.HasPrefix(posting, ";") {
+			continue
+		}
+		account, _, _ := strings.Cut(posting, ";")
+		account, _, _ = strings.Cut(account, "\t")
+		account, _, _ = strings.Cut(account, "  ")
+		account = strings.TrimSpace(account)
+		if date.After(f.Last[account]) {
+			f.Last[account] = date
+		}
+	}
+	return f, nil
+}
+
+// checkLedgerOrder returns an error when an entry the existing file lacks is
+// dated before the file's last entry on an account with a balance assertion
+// or opening balance. hledger checks assertions in date order, but Ledger
+// checks them in file order, so appending an older month after a newer one
+// would break them there. Shared accounts such as Expenses:* carry no
+// assertion and may go back in time.
+func checkLedgerOrder(j Journal, existing LedgerFile) error {
+	asserted := make(map[string]bool)
+	for _, pad := range j.Opening {
+		asserted[pad.Account], asserted[pad.From] = true, true
+	}
+	for _, stmt := range j.Statements {
+		if !stmt.BalanceDate.IsZero() {
+			asserted[stmt.Account] = true
+		}
+	}
+
+	check := func(id string, date time.Time, accounts ...string) error {
+		if existing.IDs[id] {
+			return nil
+		}
+		for _, account := range accounts {
+			if last := existing.Last[account]; asserted[account] && date.Before(last) {
+				return fmt.Errorf("%s entry on %s is older than the last %s entry in the file, on %s; export older statements to a new file",
+					id, date.Format(ledgerDate), account, last.Format(ledgerDate))
+			}
+		}
+		return nil
+	}
+
+	for _, pad := range j.Opening {
+		if err := check("opening:"+pad.Account, j.Start, pad.Account, pad.From); err != nil {
+			return err
+		}
+	}
+	id := ledgerIDs()
+	for _, stmt := range j.Statements {
+		for _, tx := range stmt.Transactions {
+			accounts := make([]string, len(tx.Postings))
+			for i, p := range tx.Postings {
+				accounts[i] = p.Account
+			}
+			if err := check(id(tx.Fingerprint), tx.Date, accounts...); err != nil {
+				return err
+			}
+		}
+		if !stmt.BalanceDate.IsZero() {
+			if err := check("balance:"+stmt.Period+":"+stmt.Account, stmt.BalanceDate, stmt.Account); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ledgerIDs returns a function giving the entry ID of each transaction
+// fingerprint in journal order. Identical transactions on the same day share
+// a fingerprint, so repeats are numbered to keep each exactly once.
+func ledgerIDs() func(fingerprint string) string {
+	seen := make(map[string]int)
+	return func(fingerprint string) string {
+		if seen[fingerprint]++; seen[fingerprint] > 1 {
+			return fmt.Sprintf("%s-%d", fingerprint, seen[fingerprint])
+		}
+		return fingerprint
+	}
+}
+
+// WriteLedger writes a journal in Ledger/hledger syntax, leaving out what
+// the existing file already has. Every entry carries a Fingerprint tag so
+// that exporting the same statements again adds nothing. It returns the
+// number of transactions written. Nothing is written when a new entry would
+// come before an older entry of the file on an account with an assertion.
+func WriteLedger(w io.Writer, j Journal, existing LedgerFile) (int, error) {
+	if err := checkLedgerOrder(j, existing); err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	nextID := ledgerIDs()
+	written := 0
+
+	// blank separates entries, and an append from what is already there
+	first := len(existing.IDs) == 0 && len(existing.Accounts) == 0
+	blank := func() {
+		if !first {
+			bw.WriteString("\n")
+		}
+		first = false
+	}
+
+	declared := false
+	for _, account := range j.Accounts {
+		if !existing.Accounts[account] {
+			if !declared {
+				blank()
+				declared = true
+			}
+			fmt.Fprintf(bw, "account %s\n", account)
+		}
+	}
+
+	for _, pad := range j.Opening {
+		id := "opening:" + pad.Account
+		if existing.IDs[id] {
+			continue
+		}
+		blank()
+		fmt.Fprintf(bw, "%s Opening balance\n", j.Start.Format(ledgerDate))
+		fmt.Fprintf(bw, "    ; Fingerprint: %s\n", id)
+		fmt.Fprintf(bw, "    %s  %s\n", pad.Account, ledgerAmount(pad.Balance))
+		fmt.Fprintf(bw, "    %s\n", pad.From)
+	}
+
+	for _, stmt := range j.Statements {
+		for _, tx := range stmt.Transactions {
+			id := nextID(tx.Fingerprint)
+			if existing.IDs[id] {
+				continue
+			}
+
+			blank()
+			desc := ledgerText(tx.Narration)
+			if tx.Payee != "" {
+				desc = ledgerText(tx.Payee) + " | " + desc
+			}
+			fmt.Fprintf(bw, "%s * %s\n", tx.Date.Format(ledgerDate), desc)
+			for _, m := range tx.Meta {
+				if m.Key == "note" {
+					fmt.Fprintf(bw, "    ; %s\n", ledgerText(m.Value))
+				} else {
+					fmt.Fprintf(bw, "    ; %s: %s\n", ledgerMetaKey(m.Key), ledgerText(m.Value))
+				}
+			}
+			if !tx.Transacted.IsZero() {
+				fmt.Fprintf(bw, "    ; TransactionDate: %s\n", tx.Transacted.Format("2006-01-02"))
+			}
+			if !tx.Posted.IsZero() {
+				fmt.Fprintf(bw, "    ; PostingDate: %s\n", tx.Posted.Format("2006-01-02"))
+			}
+			if len(tx.Tags) > 0 {
+				tags := make([]string, len(tx.Tags))
+				for i, tag := range tx.Tags {
+					tags[i] = strings.ReplaceAll(tag, ":", "-")
+				}
+				fmt.Fprintf(bw, "    ; :%s:\n", strings.Join(tags, ":"))
+			}
+			fmt.Fprintf(bw, "    ; Fingerprint: %s\n", id)
+			for _, p := range tx.Postings {
+				fmt.Fprintf(bw, "    %s  %s", p.Account, ledgerAmount(p.Amount))
+				if !p.Price.IsZero() {
+					fmt.Fprintf(bw, " @@ %s", ledgerAmount(p.Price))
+				}
+				bw.WriteString("\n")
+			}
+			written++
+		}
+
+		if stmt.BalanceDate.IsZero() {
+			continue
+		}
+		id := "balance:" + stmt.Period + ":" + stmt.Account
+		if existing.IDs[id] {
+			continue
+		}
+		blank()
+		fmt.Fprintf(bw, "%s Statement %s balance\n", stmt.BalanceDate.Format(ledgerDate), stmt.Period)
+		fmt.Fprintf(bw, "    ; Fingerprint: %s\n", id)
+		fmt.Fprintf(bw, "    %s  0 %s = %s\n", stmt.Account, stmt.Balance.Currency, ledgerAmount(stmt.Balance))
+	}
+	return written, bw.Flush()
+}
+
+// ExportLedgerFile appends the entries of a journal that are not in the
+// file yet, creating it when missing, or writes them all to stdout for "-"
+func ExportLedgerFile(path string, j Journal) (int, error) {
+	if path == "-" {
+		return WriteLedger(os.Stdout, j, LedgerFile{})
+	}
+
+	existing, err := ReadLedgerFile(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := WriteLedger(f, j, existing)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// ledgerAmount renders an amount with its commodity, e.g. -430.00 TWD
+func ledgerAmount(m Money) string {
+	return m.String() + " " + m.Currency
+}
+
+// ledgerText keeps free text on one line
+func ledgerText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ledgerMetaKey capitalizes a metadata key, e.g. location becomes Location
+func ledgerMetaKey(key string) string {
+	return strings.ToUpper(key[:1]) + key[1:]
+}
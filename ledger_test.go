@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportLedger builds the journal of statements and appends it to a file
+func exportLedger(t *testing.T, path string, statements ...Statement) (int, error) {
+	t.Helper()
+	j, err := BuildJournal(statements, defaultAccounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ExportLedgerFile(path, j)
+}
+
+func TestExportLedgerFileIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cards.journal")
+	statements := journalStatements()
+	// Two identical purchases on one day share a fingerprint
+	statements[1].Transactions = append(statements[1].Transactions, statements[1].Transactions[0])
+
+	n, err := exportLedger(t, path, statements...)
+	if err != nil || n != 4 {
+		t.Fatalf("first export wrote %d transactions (%v), want 4", n, err)
+	}
+	first, _ := os.ReadFile(path)
+
+	n, err = exportLedger(t, path, statements...)
+	if err != nil || n != 0 {
+		t.Fatalf("second export wrote %d transactions (%v), want 0", n, err)
+	}
+	second, _ := os.ReadFile(path)
+	if string(first) != string(second) {
+		t.Errorf("second export changed the file:\n%s", second[len(first):])
+	}
+	if got := strings.Count(string(second), "Fingerprint: "); got != 6 {
+		t.Errorf("file has %d entries, want 4 transactions and 2 balances", got)
+	}
+}
+
+func TestExportLedgerFileAppendsInDateOrder(t *testing.T) {
+	april, march := journalStatements()[0], journalStatements()[1]
+
+	t.Run("newer month appended", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cards.journal")
+		if _, err := exportLedger(t, path, march); err != nil {
+			t.Fatal(err)
+		}
+		n, err := exportLedger(t, path, march, april)
+		if err != nil || n != 2 {
+			t.Fatalf("appending April wrote %d transactions (%v), want 2", n, err)
+		}
+	})
+
+	t.Run("older month refused", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cards.journal")
+		if _, err := exportLedger(t, path, april); err != nil {
+			t.Fatal(err)
+		}
+		before, _ := os.ReadFile(path)
+
+		n, err := exportLedger(t, path, march)
+		if err == nil || !strings.Contains(err.Error(), "older than the last") {
+			t.Fatalf("appending March after April wrote %d transactions, err %v", n, err)
+		}
+		after, _ := os.ReadFile(path)
+		if string(before) != string(after) {
+			t.Errorf("refused export changed the file:\n%s", after[len(before):])
+		}
+	})
+}
+
+func TestReadLedgerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cards.journal")
+	journal := `account Liabilities:CreditCard
+
+2025/03/03 * CAFE
+    ; Fingerprint: abc
+    Expenses:Food  100.00 TWD
+    Liabilities:CreditCard  -100.00 TWD
+
+2025/03/26 Statement 2025/03 balance
+    ; Fingerprint: balance:2025/03:Liabilities:CreditCard
+    Liabilities:CreditCard  0 TWD = 100.00 TWD
+`
+	if err := os.WriteFile(path, []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ReadLedgerFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IDs["abc"] || !f.IDs["balance:2025/03:Liabilities:CreditCard"] || !f.Accounts["Liabilities:CreditCard"] {
+		t.Errorf("ReadLedgerFile() = %+v", f)
+	}
+	if !f.Last["Expenses:Food"].Equal(day("2025-03-03")) || !f.Last["Liabilities:CreditCard"].Equal(day("2025-03-26")) {
+		t.Errorf("last entries = %v", f.Last)
+	}
+
+	// Hand-written entries may separate amounts with a tab and leave the
+	// amount off a posting with a comment
+	handwritten := "2025/04/02 Groceries\n\tExpenses:Food\t250.00 TWD\n    Liabilities:CreditCard  ; paid later\n"
+	if err := os.WriteFile(path, []byte(journal+"\n"+handwritten), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err = ReadLedgerFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Last["Expenses:Food"].Equal(day("2025-04-02")) || !f.Last["Liabilities:CreditCard"].Equal(day("2025-04-02")) || len(f.Last) != 2 {
+		t.Errorf("last entries of hand-written journal = %v", f.Last)
+	}
+
+	missing, err := ReadLedgerFile(filepath.Join(t.TempDir(), "none.journal"))
+	if err != nil || len(missing.IDs) != 0 {
+		t.Errorf("missing file = %+v, %v", missing, err)
+	}
+}
+
+func TestExportLedgerFileAppendsOtherCard(t *testing.T) {
+	accounts := defaultAccounts
+	accounts.Cards = map[string]string{"1234": "Liabilities:Card1234", "9999": "Liabilities:Card9999"}
+	export := func(path string, stmt Statement) (int, error) {
+		j, err := BuildJournal([]Statement{stmt}, accounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ExportLedgerFile(path, j)
+	}
+
+	// Card 1234's April statement books Expenses:Food on 04/11
+	path := filepath.Join(t.TempDir(), "cards.journal")
+	if _, err := export(path, journalStatements()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Card 9999 closes on 04/15 with an earlier Expenses:Food purchase
+	other := journalTx("COFFEE", 150, "2025-04-04", "2025-04-05")
+	other.CardNo = "9999"
+	stmt := Statement{
+		StmtYr: "2025", StmtMo: "04", Currency: "TWD",
+		Period: day("2025-04-01"), StatementDate: day("2025-04-15"),
+		PreviousBalance: twd(500), Total: twd(650),
+		Transactions: []Transaction{other},
+	}
+	n, err := export(path, stmt)
+	if err != nil || n != 1 {
+		t.Fatalf("appending card 9999 wrote %d transactions (%v), want 1", n, err)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementOverlap describes a statement that appears in more than one file
+type StatementOverlap struct {
+	Key   string
+	Files []string
+}
+
+// LoadReport describes how statements from several files were merged
+type LoadReport struct {
+	Files     []string
+	Overlaps  []StatementOverlap // Same statement with identical content
+	Conflicts []StatementOverlap // Same statement with differing content
+	Issues    []string           // Values that could not be parsed
+}
+
+// HasIssues reports whether any statement overlapped or failed to parse
+func (r LoadReport) HasIssues() bool {
+	return len(r.Overlaps) > 0 || len(r.Conflicts) > 0 || len(r.Issues) > 0
+}
+
+// String renders the report in a human readable form
+func (r LoadReport) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Loaded %d file(s)\n", len(r.Files)))
+	for _, o := range r.Overlaps {
+		b.WriteString(fmt.Sprintf("  duplicate %s in %s\n", o.Key, strings.Join(o.Files, ", ")))
+	}
+	for _, c := range r.Conflicts {
+		b.WriteString(fmt.Sprintf("  conflict  %s in %s (kept first)\n", c.Key, strings.Join(c.Files, ", ")))
+	}
+	for _, issue := range r.Issues {
+		b.WriteString(fmt.Sprintf("  invalid   %s\n", issue))
+	}
+	return b.String()
+}
+
+// ExpandInputs resolves files, directories and glob patterns into a list of files
+func ExpandInputs(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(path)
+				continue
+			}
+
+			// Directories contribute the files a parser recognizes, non-recursively
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				file := filepath.Join(path, entry.Name())
+				if canParse(file) {
+					add(file)
+				}
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no statement files found")
+	}
+
+	return files, nil
+}
+
+// statementKey identifies a statement across files
+func statementKey(stmt Statement) string {
+	return fmt.Sprintf("%s/%s %s", stmt.StmtYr, stmt.StmtMo, stmt.PaymentKey)
+}
+
+// sameStatement compares two statements ignoring where they were loaded from
+func sameStatement(a, b Statement) bool {
+	a.Source = ""
+	b.Source = ""
+	return reflect.DeepEqual(a, b)
+}
+
+// LoadStatementFiles loads and merges statements from files, directories or globs
+func LoadStatementFiles(args []string) ([]Statement, LoadReport, error) {
+	report := LoadReport{}
+
+	files, err := ExpandInputs(args)
+	if err != nil {
+		return nil, report, err
+	}
+	report.Files = files
+
+	var merged []Statement
+	index := make(map[string]int)         // key -> position in merged
+	overlaps := make(map[string][]string) // key -> files containing it
+	conflicting := make(map[string]bool)
+	var order []string
+
+	for _, file := range files {
+		statements, err := LoadStatements(file)
+		if err != nil {
+			return nil, report, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, stmt := range statements {
+			stmt.Source = file
+			key := statementKey(stmt)
+
+			pos, exists := index[key]
+			if !exists {
+				index[key] = len(merged)
+				merged = append(merged, stmt)
+				continue
+			}
+
+			// CSV exports may split a billing month across files
+			if isCSVStatement(stmt) {
+				added, err := mergeCSVStatement(&merged[pos], stmt)
+				if err != nil {
+					return nil, report, fmt.Errorf("%s: %s: %w", file, key, err)
+				}
+				if added > 0 {
+					merged[pos].Source += ", " + file
+					continue
+				}
+			}
+
+			if len(overlaps[key]) == 0 {
+				order = append(order, key)
+				overlaps[key] = []string{merged[pos].Source}
+			}
+			overlaps[key] = append(overlaps[key], file)
+			if !isCSVStatement(stmt) && !sameStatement(merged[pos], stmt) {
+				conflicting[key] = true
+			}
+		}
+	}
+
+	for _, key := range order {
+		overlap := StatementOverlap{Key: key, Files: overlaps[key]}
+		if conflicting[key] {
+			report.Conflicts = append(report.Conflicts, overlap)
+		} else {
+			report.Overlaps = append(report.Overlaps, overlap)
+		}
+	}
+
+	report.Issues = normalizeStatements(merged)
+
+	// Newest statement first, regardless of file order
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Period.After(merged[j].Period)
+	})
+
+	return merged, report, nil
+}
+
+// normalizeStatements parses the raw string fields of every statement into
+// their typed counterparts, returning a description of each value that
+// could not be parsed
+func normalizeStatements(statements []Statement) []string {
+	var issues []string
+
+	for i := range statements {
+		stmt := &statements[i]
+		if stmt.Currency == "" {
+			stmt.Currency = "TWD"
+		}
+
+		where := fmt.Sprintf("%s: %s", stmt.Source, statementKey(*stmt))
+		for _, issue := range stmt.Issues {
+			issues = append(issues, fmt.Sprintf("%s: %s", where, issue))
+		}
+		parse := func(field, value, currency string) Money {
+			m, err := ParseMoney(value, currency)
+			if err != nil {
+				issues = append(issues, fmt.Sprintf("%s: %s: %v", where, field, err))
+			}
+			return m
+		}
+		parseDate := func(field, value string, refYear int) time.Time {
+			t, err := ParseDate(value, refYear)
+			if err != nil {
+				issues = append(issues, fmt.Sprintf("%s: %s: %v", where, field, err))
+			}
+			return t
+		}
+
+		year, errYear := parseYear(strings.TrimSpace(stmt.StmtYr))
+		month, errMonth := strconv.Atoi(strings.TrimSpace(stmt.StmtMo))
+		if errYear != nil || errMonth != nil || month < 1 || month > 12 {
+			issues = append(issues, fmt.Sprintf("%s: invalid statement period", where))
+		} else {
+			stmt.Period = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		}
+
+		stmt.StatementDate = parseDate("stmtDate", stmt.StmtDate, year)
+		stmt.PaymentDue = parseDate("pmtDue", stmt.PmtDue, year)
+
+		stmt.Total = parse("curTotAmt", stmt.CurTotAmt, stmt.Currency)
+		stmt.MinPayment = parse("minAmt", stmt.MinAmt, stmt.Currency)
+		stmt.CreditLimit = parse("creditLmt", stmt.CreditLmt, stmt.Currency)
+		stmt.CashAdvanceLimit = parse("cashAdvLmt", stmt.CashAdvLmt, stmt.Currency)
+		stmt.PreviousBalance = parse("preBal", stmt.PreBal, stmt.Currency)
+
+		for j := range stmt.Transactions {
+			tx := &stmt.Transactions[j]
+			txWhere := fmt.Sprintf("transaction %d (%s)", j+1, strings.TrimSpace(tx.Description))
+
+			currency := strings.TrimSpace(tx.AmtCy)
+			domestic := currency == "" || currency == stmt.Currency
+			if domestic {
+				currency = stmt.Currency
+			}
+
+			tx.TransactionDate = parseTxDate(parseDate, txWhere+": txnDate", tx.TxnDate, stmt)
+			tx.PostedDate = parseTxDate(parseDate, txWhere+": postingDate", tx.PostingDate, stmt)
+			tx.ConversionDate = parseTxDate(parseDate, txWhere+": cyCnvDate", tx.CyCnvDate, stmt)
+
+			tx.OriginalAmount = parse(txWhere+": amount", tx.Amount, currency)
+			tx.BilledAmount = parse(txWhere+": ntdAmount", tx.NtdAmount, stmt.Currency)
+
+			// Domestic rows may carry only one of the two amounts
+			if domestic && tx.BilledAmount.IsZero() {
+				tx.BilledAmount = tx.OriginalAmount
+			}
+			if domestic && tx.OriginalAmount.IsZero() {
+				tx.OriginalAmount = tx.BilledAmount
+			}
+		}
+	}
+
+	return issues
+}
+
+// parseTxDate parses a transaction date. Dates without a year take the
+// statement's year, moving back a year when that would place them after the
+// statement (a December purchase on a January statement).
+func parseTxDate(parseDate func(string, string, int) time.Time, field, value string, stmt *Statement) time.Time {
+	end := stmt.StatementDate
+	if end.IsZero() && !stmt.Period.IsZero() {
+		end = stmt.Period.AddDate(0, 1, -1)
+	}
+
+	// Without a statement date a date lacking its year is reported rather
+	// than placed in year 1
+	refYear := 0
+	if !end.IsZero() {
+		refYear = end.Year()
+	}
+	t := parseDate(field, value, refYear)
+	yearless := len(strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '-' || r == '.' })) == 2
+	if yearless && !t.IsZero() && t.After(end) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hsbcStatement is a minimal statement in the HSBC JSON export layout
+func hsbcStatement(month, total string) string {
+	return `{"stmtYr": "2025", "stmtMo": "` + month + `", "paymentKey": "PK1", "curTotAmt": "` + total + `",
+		"transactions": [{"description": "CAFE", "txnDate": "2025/` + month + `/02", "ntdAmount": "` + total + `", "cardNo": "1234"}]}`
+}
+
+// writeFiles writes files named after the keys of files into dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.json":       "[" + hsbcStatement("03", "100") + "]",
+		"b.json":       "[" + hsbcStatement("04", "200") + "]",
+		"notes.txt":    "not a statement",
+		".hidden.json": "[" + hsbcStatement("05", "300") + "]",
+	})
+	if err := os.Mkdir(filepath.Join(dir, "old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(dir, "old"), map[string]string{"c.json": "[" + hsbcStatement("02", "50") + "]"})
+	if err := os.Mkdir(filepath.Join(dir, "misc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(dir, "misc"), map[string]string{"notes.txt": "not a statement"})
+	path := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(dir, name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		err  string
+	}{
+		{"directory", []string{dir}, path("a.json", "b.json"), ""},
+		{"glob", []string{filepath.Join(dir, "?.json")}, path("a.json", "b.json"), ""},
+		{"file", path("notes.txt"), path("notes.txt"), ""},
+		{"repeated", []string{filepath.Join(dir, "b.json"), filepath.Join(dir, "?.json")}, path("b.json", "a.json"), ""},
+		{"glob matching nothing", []string{filepath.Join(dir, "*.ofx")}, nil, "no files match"},
+		{"missing file", path("none.json"), nil, "no such file"},
+		{"directory without statements", path("misc"), nil, "no statement files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandInputs(tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ExpandInputs() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandInputs() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadStatementFilesDeduplicates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.json": "[" + hsbcStatement("03", "100") + "," + hsbcStatement("04", "200") + "]",
+		"b.json": "[" + hsbcStatement("03", "100") + "," + hsbcStatement("04", "250") + "]",
+	})
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+
+	statements, report, err := LoadStatementFiles([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 || statements[0].StmtMo != "04" || statements[1].StmtMo != "03" {
+		t.Fatalf("got %d statements, want April and March once each", len(statements))
+	}
+	if statements[0].CurTotAmt != "200" || statements[0].Source != a {
+		t.Errorf("conflicting April statement kept from %s with total %s, want the first file's", statements[0].Source, statements[0].CurTotAmt)
+	}
+	if want := []StatementOverlap{{Key: "2025/03 PK1", Files: []string{a, b}}}; !reflect.DeepEqual(report.Overlaps, want) {
+		t.Errorf("Overlaps = %+v, want %+v", report.Overlaps, want)
+	}
+	if want := []StatementOverlap{{Key: "2025/04 PK1", Files: []string{a, b}}}; !reflect.DeepEqual(report.Conflicts, want) {
+		t.Errorf("Conflicts = %+v, want %+v", report.Conflicts, want)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("Issues = %v", report.Issues)
+	}
+}
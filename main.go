@@ -1,10 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -17,15 +17,41 @@ type viewMode int
 const (
 	summaryView viewMode = iota
 	statementsView
+	merchantsView
 )
 
 type sortMode int
 
+// editMode is the value being typed into the input line, if any
+type editMode int
+
+const (
+	editNone editMode = iota
+	editTags
+	editNote
+	editTagFilter
+	editSearch
+	editExport
+)
+
 const (
 	sortByDate sortMode = iota
 	sortByAmount
 	sortByLocation
 	sortByCategory
+	sortByMerchant
+)
+
+// sortLabels names the sort modes in cycling order
+var sortLabels = []string{"Date", "Amount", "Location", "Category", "Merchant"}
+
+// merchantSortMode ranks the merchant leaderboard
+type merchantSortMode int
+
+const (
+	rankBySpend merchantSortMode = iota
+	rankByVisits
+	rankByAverage
 )
 
 type model struct {
@@ -36,17 +62,43 @@ type model struct {
 	// For statements view
 	selectedStmtIdx   int
 	sortBy            sortMode
-	categoryFilter    string // Current category filter
+	filter            Query // Category tab and tag filter
+	categories        CategorySet
+	tabOffset         int // First category tab bound to key 1
 	statementsTable   table.Model
 	transactionsTable table.Model
-	focusedTable      int // 0 = statements, 1 = transactions
+	focusedTable      int              // 0 = statements, 1 = transactions
+	visibleTxs        []Transaction    // Transactions behind the table rows, in row order
+	visibleStmts      []int            // Statement of each entry in visibleTxs
+	rows              []TransactionRow // Every table row, including the foreign fee row
+	search            string           // Search query; while set the table lists matches from every statement
+	searchQuery       Query            // Last valid parse of the search query
+	searchErr         string           // Why the search query as typed does not parse
+
+	// Category overrides
+	categorizer  *Categorizer
+	overrides    *OverrideStore
+	picking      bool // Category picker is open
+	pickerCursor int
+	showDetail   bool // Detail pane for the selected transaction is open
+	editing      editMode
+	input        string // Text typed into the input line
+	status       string // Result of the last action, shown under the views
+	exportAll    bool   // Export every statement rather than the table
+
+	// For merchants view
+	merchantTotals   []MerchantTotal // Leaderboard rows, in table order
+	merchantRank     merchantSortMode
+	merchantsTable   table.Model
+	merchantTxTable  table.Model
+	selectedMerchant int
 
 	width  int
 	height int
 	ready  bool
 }
 
-func initialModel(statements []Statement, categorized CategorizedTransactions) model {
+func initialModel(statements []Statement, categorized CategorizedTransactions, categories CategorySet, categorizer *Categorizer, overrides *OverrideStore) model {
 	// Create statements table
 	stmtColumns := []table.Column{
 		{Title: "Date", Width: 12},
@@ -55,10 +107,9 @@ func initialModel(statements []Statement, categorized CategorizedTransactions) m
 
 	stmtRows := []table.Row{}
 	for _, stmt := range statements {
-		formattedAmt := formatAmountString(stmt.CurTotAmt)
 		stmtRows = append(stmtRows, table.Row{
 			fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo),
-			rightPadAmount(fmt.Sprintf("NT$%s", formattedAmt), 18),
+			rightPadAmount(stmt.Total.Display(), 18),
 		})
 	}
 
@@ -84,9 +135,9 @@ func initialModel(statements []Statement, categorized CategorizedTransactions) m
 	// Create transactions table (initially empty)
 	txColumns := []table.Column{
 		{Title: "Date", Width: 10},
-		{Title: "Category", Width: 10},
+		{Title: "Category", Width: 14},
 		{Title: "Amount (NTD)", Width: 13},
-		{Title: "Description", Width: 28},
+		{Title: "Description", Width: 24},
 		{Title: "Amount", Width: 13},
 		{Title: "Curr", Width: 5},
 		{Title: "Loc", Width: 8},
@@ -117,17 +168,46 @@ func initialModel(statements []Statement, categorized CategorizedTransactions) m
 		currentView:       summaryView,
 		selectedStmtIdx:   0,
 		sortBy:            sortByDate,
-		categoryFilter:    CategoryAll,
+		categories:        categories,
+		categorizer:       categorizer,
+		overrides:         overrides,
 		statementsTable:   stmtTable,
 		transactionsTable: txTable,
 		focusedTable:      0, // Start with statements table focused
 		ready:             false,
 	}
 
+	// Merchant leaderboard and the transactions of the selected merchant
+	m.merchantsTable = table.New(
+		table.WithColumns([]table.Column{
+			{Title: "#", Width: 3},
+			{Title: "Merchant", Width: 22},
+			{Title: "Visits", Width: 6},
+			{Title: "Total", Width: 16},
+			{Title: "Average", Width: 14},
+		}),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	m.merchantsTable.SetStyles(stmtTableStyles)
+
+	m.merchantTxTable = table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Date", Width: 10},
+			{Title: "Category", Width: 14},
+			{Title: "Amount", Width: 13},
+			{Title: "Description", Width: 22},
+			{Title: "Loc", Width: 8},
+		}),
+		table.WithHeight(10),
+	)
+	m.merchantTxTable.SetStyles(txTableStyles)
+
 	// Initialize transactions table for the first statement
 	if len(statements) > 0 {
 		m = m.updateTransactionsTable()
 	}
+	m = m.updateMerchantsTable()
 
 	return m
 }
@@ -146,27 +226,150 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ready = true
 
 		// Update table heights
-		tableHeight := m.height - 10
+		tableHeight := m.height - 11
 		if tableHeight < 5 {
 			tableHeight = 5
 		}
 		m.statementsTable.SetHeight(tableHeight)
 		m.transactionsTable.SetHeight(tableHeight)
+		m.merchantsTable.SetHeight(tableHeight)
+		m.merchantTxTable.SetHeight(tableHeight)
 
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.picking && msg.String() != "ctrl+c" {
+			return m.updatePicker(msg), nil
+		}
+		if m.editing != editNone && msg.String() != "ctrl+c" {
+			return m.updateEditor(msg), nil
+		}
+		if m.showDetail {
+			switch msg.String() {
+			case "esc", "enter", "q":
+				m.showDetail = false
+				return m, nil
+			case "c", "t", "n":
+				m.showDetail = false
+			case "ctrl+c":
+			default:
+				return m, nil
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
 
+		case "enter":
+			// Open the transactions of the selected merchant
+			if m.currentView == merchantsView && m.focusedTable == 0 {
+				m.focusedTable = 1
+				m.merchantsTable.Blur()
+				m.merchantTxTable.Focus()
+				return m, nil
+			}
+
+			// Go to the statement of the selected search result
+			if m.currentView == statementsView && m.focusedTable == 1 && m.search != "" {
+				return m.jumpToStatement(), nil
+			}
+
+			// Show details and the category trace of the selected transaction
+			if m.currentView == statementsView && m.focusedTable == 1 {
+				if _, ok := m.selectedTransaction(); ok {
+					m.showDetail = true
+				}
+			}
+			return m, nil
+
+		case "t", "n":
+			// Edit the tags or note of the selected transaction
+			if m.currentView == statementsView && m.focusedTable == 1 {
+				if tx, ok := m.selectedTransaction(); ok {
+					m.showDetail = false
+					if msg.String() == "t" {
+						m.editing = editTags
+						m.input = FormatTags(tx.Tags)
+					} else {
+						m.editing = editNote
+						m.input = tx.Note
+					}
+				}
+			}
+			return m, nil
+
+		case "/":
+			// Search transactions across every statement
+			if m.currentView == statementsView {
+				m.editing = editSearch
+				m.input = m.search
+				m.focusedTable = 1
+				m.statementsTable.Blur()
+				m.transactionsTable.Focus()
+			}
+			return m, nil
+
+		case "esc":
+			// Leave the search results
+			if m.currentView == statementsView && m.search != "" {
+				m.search, m.searchQuery = "", Query{}
+				m = m.updateTransactionsTable()
+			}
+			return m, nil
+
+		case "e", "E":
+			// Export the table, or every statement with the same filter and sort
+			if m.currentView == statementsView {
+				m.editing = editExport
+				m.exportAll = msg.String() == "E"
+				switch {
+				case m.exportAll:
+					m.input = "statements.csv"
+				case m.search != "":
+					m.input = "search.csv"
+				case m.selectedStmtIdx < len(m.statements):
+					stmt := m.statements[m.selectedStmtIdx]
+					m.input = fmt.Sprintf("statements-%s-%s.csv", stmt.StmtYr, stmt.StmtMo)
+				}
+			}
+			return m, nil
+
+		case "#":
+			// Filter transactions by tag
+			if m.currentView == statementsView {
+				m.editing = editTagFilter
+				m.input = ""
+				if tag := m.filter.Tag(); tag != "" {
+					m.input = "#" + tag
+				}
+			}
+			return m, nil
+
+		case "c":
+			// Pick a category for the selected transaction
+			if m.currentView == statementsView && m.focusedTable == 1 {
+				if _, ok := m.selectedTransaction(); ok {
+					m.picking = true
+					m.pickerCursor = 0
+				}
+			}
+			return m, nil
+
 		case "tab":
-			if m.currentView == summaryView {
+			// Cycle Summary → Statements → Merchants
+			switch m.currentView {
+			case summaryView:
 				m.currentView = statementsView
 				m.focusedTable = 0
 				m.statementsTable.Focus()
 				m.transactionsTable.Blur()
-			} else {
+			case statementsView:
+				m.currentView = merchantsView
+				m.focusedTable = 0
+				m.merchantsTable.Focus()
+				m.merchantTxTable.Blur()
+			default:
 				m.currentView = summaryView
 			}
 			return m, nil
@@ -179,6 +382,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.transactionsTable.Blur()
 				return m, nil
 			}
+			if m.currentView == merchantsView && m.focusedTable == 1 {
+				m.focusedTable = 0
+				m.merchantsTable.Focus()
+				m.merchantTxTable.Blur()
+				return m, nil
+			}
 
 		case "right", "l":
 			// Switch to transactions table (right panel)
@@ -188,68 +397,48 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.transactionsTable.Focus()
 				return m, nil
 			}
+			if m.currentView == merchantsView && m.focusedTable == 0 {
+				m.focusedTable = 1
+				m.merchantsTable.Blur()
+				m.merchantTxTable.Focus()
+				return m, nil
+			}
 
 		case "s":
 			// Cycle through sort modes
-			if m.currentView == statementsView {
-				m.sortBy = (m.sortBy + 1) % 4
-				m = m.updateTransactionsTable()
-			}
-			return m, nil
-
-		case "1":
-			// Filter: All transactions
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryAll
-				m = m.updateTransactionsTable()
-			}
-			return m, nil
-
-		case "2":
-			// Filter: Food
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryFood
-				m = m.updateTransactionsTable()
-			}
-			return m, nil
-
-		case "3":
-			// Filter: Transport
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryTransport
+			switch m.currentView {
+			case statementsView:
+				m.sortBy = (m.sortBy + 1) % sortMode(len(sortLabels))
 				m = m.updateTransactionsTable()
+			case merchantsView:
+				m.merchantRank = (m.merchantRank + 1) % 3
+				m = m.updateMerchantsTable()
 			}
 			return m, nil
 
-		case "4":
-			// Filter: Shopping
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Filter by the category tab bound to this key
 			if m.currentView == statementsView {
-				m.categoryFilter = CategoryShopping
-				m = m.updateTransactionsTable()
-			}
-			return m, nil
-
-		case "5":
-			// Filter: Travel
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryTravel
-				m = m.updateTransactionsTable()
+				tabs := m.categoryTabs()
+				idx := m.tabOffset + int(msg.String()[0]-'1')
+				if idx < len(tabs) {
+					m.filter = m.filter.WithCategory(tabs[idx])
+					m = m.updateTransactionsTable()
+				}
 			}
 			return m, nil
 
-		case "6":
-			// Filter: Utilities
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryUtilities
-				m = m.updateTransactionsTable()
+		case "[":
+			// Scroll the category tab bar left
+			if m.currentView == statementsView && m.tabOffset > 0 {
+				m.tabOffset--
 			}
 			return m, nil
 
-		case "7":
-			// Filter: Other
-			if m.currentView == statementsView {
-				m.categoryFilter = CategoryOther
-				m = m.updateTransactionsTable()
+		case "]":
+			// Scroll the category tab bar right
+			if m.currentView == statementsView && m.tabOffset+maxCategoryTabs < len(m.categoryTabs()) {
+				m.tabOffset++
 			}
 			return m, nil
 
@@ -413,6 +602,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Update tables when in merchants view
+	if m.currentView == merchantsView {
+		if m.focusedTable == 0 {
+			m.merchantsTable, cmd = m.merchantsTable.Update(msg)
+			if m.merchantsTable.Cursor() != m.selectedMerchant {
+				m.selectedMerchant = m.merchantsTable.Cursor()
+				m = m.updateMerchantTxTable()
+			}
+		} else {
+			m.merchantTxTable, cmd = m.merchantTxTable.Update(msg)
+		}
+		return m, cmd
+	}
+
 	// Update tables when in statements view
 	if m.currentView == statementsView {
 		if m.focusedTable == 0 {
@@ -436,77 +639,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // updateTransactionsTable rebuilds the transactions table based on selected statement and sort mode
 func (m model) updateTransactionsTable() model {
+	if m.search != "" {
+		return m.updateSearchResults()
+	}
 	if m.selectedStmtIdx >= len(m.statements) {
 		return m
 	}
 
 	stmt := m.statements[m.selectedStmtIdx]
-
-	// Separate foreign fees from other transactions
-	var regularTxs []Transaction
-	var foreignFeeTxs []Transaction
-	foreignFeeTotal := 0.0
-
-	for _, tx := range stmt.Transactions {
-		normalizedDesc := ToCDB(tx.Description)
-		if strings.HasPrefix(normalizedDesc, "國外交易手續費") {
-			foreignFeeTxs = append(foreignFeeTxs, tx)
-			amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-			if amt == 0 {
-				amt, _ = strconv.ParseFloat(tx.Amount, 64)
-			}
-			foreignFeeTotal += amt
-		} else {
-			regularTxs = append(regularTxs, tx)
-		}
-	}
-
-	// Filter by category
-	var filteredTxs []Transaction
-	if m.categoryFilter == CategoryAll {
-		filteredTxs = regularTxs
-	} else {
-		for _, tx := range regularTxs {
-			if tx.Category == m.categoryFilter {
-				filteredTxs = append(filteredTxs, tx)
-			}
-		}
-	}
-
-	transactions := make([]Transaction, len(filteredTxs))
-	copy(transactions, filteredTxs)
-
-	// Sort transactions
-	switch m.sortBy {
-	case sortByDate:
-		sort.Slice(transactions, func(i, j int) bool {
-			return transactions[i].TxnDate < transactions[j].TxnDate
-		})
-	case sortByAmount:
-		sort.Slice(transactions, func(i, j int) bool {
-			amtI, _ := strconv.ParseFloat(transactions[i].NtdAmount, 64)
-			amtJ, _ := strconv.ParseFloat(transactions[j].NtdAmount, 64)
-			return amtI > amtJ
-		})
-	case sortByLocation:
-		sort.Slice(transactions, func(i, j int) bool {
-			return transactions[i].TxnLoc < transactions[j].TxnLoc
-		})
-	case sortByCategory:
-		sort.Slice(transactions, func(i, j int) bool {
-			return transactions[i].Category < transactions[j].Category
-		})
-	}
+	txRows := StatementRows(m.statements, m.selectedStmtIdx, m.filter, m.sortBy)
+	m.rows = txRows
 
 	// Update table columns based on filter
-	showCategoryColumn := m.categoryFilter == CategoryAll
+	showCategoryColumn := m.filter.Category() == CategoryAll
+	billedTitle := fmt.Sprintf("Amount (%s)", currencyLabel(stmt.Currency))
 	var txColumns []table.Column
 	if showCategoryColumn {
 		txColumns = []table.Column{
 			{Title: "Date", Width: 10},
-			{Title: "Category", Width: 10},
-			{Title: "Amount (NTD)", Width: 13},
-			{Title: "Description", Width: 28},
+			{Title: "Category", Width: 14},
+			{Title: billedTitle, Width: 13},
+			{Title: "Description", Width: 24},
 			{Title: "Amount", Width: 13},
 			{Title: "Curr", Width: 5},
 			{Title: "Loc", Width: 8},
@@ -514,7 +667,7 @@ func (m model) updateTransactionsTable() model {
 	} else {
 		txColumns = []table.Column{
 			{Title: "Date", Width: 10},
-			{Title: "Amount (NTD)", Width: 13},
+			{Title: billedTitle, Width: 13},
 			{Title: "Description", Width: 38},
 			{Title: "Amount", Width: 13},
 			{Title: "Curr", Width: 5},
@@ -528,83 +681,44 @@ func (m model) updateTransactionsTable() model {
 
 	// Build table rows
 	rows := []table.Row{}
-	for _, tx := range transactions {
-		// NTD amount
-		ntdAmt := tx.NtdAmount
-		if ntdAmt == "" {
-			ntdAmt = "0.00"
-		}
-
-		// Skip transactions with zero amount
-		if tx.NormalizedDescription == "網路銀行繳款" {
-			continue
-		}
-
-		// Clean and check currency (trim whitespace)
-		currency := strings.TrimSpace(tx.AmtCy)
-
-		// Original amount in foreign currency
-		originalAmt := tx.Amount
-		if originalAmt == "" {
-			originalAmt = "0.00"
+	m.visibleTxs = nil
+	m.visibleStmts = nil
+	for _, row := range txRows {
+		category := "Fee"
+		if !row.IsFee() {
+			m.visibleTxs = append(m.visibleTxs, row.Transaction)
+			m.visibleStmts = append(m.visibleStmts, row.Statement)
+			category = m.categoryCell(row.Transaction)
 		}
 
-		// For NTD transactions (empty currency), use NTD amount as the display amount
-		if currency == "" {
-			currency = "NTD"
-			originalAmt = ntdAmt
-		}
-
-		// Get clean description (remove APE prefix)
-		cleanDesc := GetCleanDescription(tx.NormalizedDescription)
-
-		if showCategoryColumn {
-			rows = append(rows, table.Row{
-				tx.TxnDate,
-				tx.Category,
-				rightPadAmount(formatAmountString(ntdAmt), 13),
-				truncate(cleanDesc, 28),
-				rightPadAmount(formatAmountString(originalAmt), 13),
-				strings.TrimSpace(currency),
-				truncate(tx.TxnLoc, 8),
-			})
-		} else {
-			rows = append(rows, table.Row{
-				tx.TxnDate,
-				rightPadAmount(formatAmountString(ntdAmt), 13),
-				truncate(cleanDesc, 38),
-				rightPadAmount(formatAmountString(originalAmt), 13),
-				strings.TrimSpace(currency),
-				truncate(tx.TxnLoc, 8),
-			})
-		}
-	}
+		// Original amount and its currency; domestic transactions were
+		// given the billed amount at load time
+		currency := currencyLabel(row.Original.Currency)
 
-	// Add aggregated foreign fee row if there are any (only for "All" filter)
-	if len(foreignFeeTxs) > 0 && m.categoryFilter == CategoryAll {
-		feeDate := stmt.StmtDate
-		if len(foreignFeeTxs) > 0 && foreignFeeTxs[0].TxnDate != "" {
-			feeDate = foreignFeeTxs[0].TxnDate
+		// Clean description followed by any tags
+		desc := row.Description
+		if len(row.Tags) > 0 {
+			desc += " " + FormatTags(row.Tags)
 		}
 
 		if showCategoryColumn {
 			rows = append(rows, table.Row{
-				feeDate,
-				"Fee",
-				rightPadAmount(formatAmount(foreignFeeTotal), 13),
-				fmt.Sprintf("Foreign TX Fee (%d)", len(foreignFeeTxs)),
-				rightPadAmount(formatAmount(foreignFeeTotal), 13),
-				"NTD",
-				"",
+				formatDate(row.Date, row.RawDate),
+				category,
+				rightPadAmount(row.Billed.Format(), 13),
+				truncate(desc, 24),
+				rightPadAmount(row.Original.Format(), 13),
+				currency,
+				truncate(row.Location, 8),
 			})
 		} else {
 			rows = append(rows, table.Row{
-				feeDate,
-				rightPadAmount(formatAmount(foreignFeeTotal), 13),
-				fmt.Sprintf("Foreign TX Fee (%d)", len(foreignFeeTxs)),
-				rightPadAmount(formatAmount(foreignFeeTotal), 13),
-				"NTD",
-				"",
+				formatDate(row.Date, row.RawDate),
+				rightPadAmount(row.Billed.Format(), 13),
+				truncate(desc, 38),
+				rightPadAmount(row.Original.Format(), 13),
+				currency,
+				truncate(row.Location, 8),
 			})
 		}
 	}
@@ -620,15 +734,84 @@ func (m model) updateTransactionsTable() model {
 	return m
 }
 
+// updateSearchResults lists the transactions of every statement that match
+// the search query and the category and tag filters
+func (m model) updateSearchResults() model {
+	txRows := MatchingRows(m.statements, m.filter.And(m.searchQuery), m.sortBy)
+	m.rows = txRows
+
+	m.transactionsTable.SetRows([]table.Row{})
+	m.transactionsTable.SetColumns([]table.Column{
+		{Title: "Date", Width: 10},
+		{Title: "Stmt", Width: 7},
+		{Title: "Category", Width: 14},
+		{Title: "Billed", Width: 16},
+		{Title: "Description", Width: 28},
+		{Title: "Loc", Width: 8},
+	})
+
+	rows := make([]table.Row, len(txRows))
+	m.visibleTxs = make([]Transaction, len(txRows))
+	m.visibleStmts = make([]int, len(txRows))
+	for i, row := range txRows {
+		desc := row.Description
+		if len(row.Tags) > 0 {
+			desc += " " + FormatTags(row.Tags)
+		}
+		rows[i] = table.Row{
+			formatDate(row.Date, row.RawDate),
+			row.Period,
+			m.categoryCell(row.Transaction),
+			rightPadAmount(row.Billed.Display(), 16),
+			truncate(desc, 28),
+			truncate(row.Location, 8),
+		}
+		m.visibleTxs[i] = row.Transaction
+		m.visibleStmts[i] = row.Statement
+	}
+	m.transactionsTable.SetRows(rows)
+	m.transactionsTable.GotoTop()
+
+	return m
+}
+
+// jumpToStatement leaves the search results and shows the statement of the
+// selected result with the cursor on that transaction
+func (m model) jumpToStatement() model {
+	tx, ok := m.selectedTransaction()
+	if !ok {
+		return m
+	}
+	m.search, m.searchQuery = "", Query{}
+	m.selectedStmtIdx = m.visibleStmts[m.transactionsTable.Cursor()]
+	m.statementsTable.SetCursor(m.selectedStmtIdx)
+	m = m.updateTransactionsTable()
+
+	fp := Fingerprint(tx)
+	for i, t := range m.visibleTxs {
+		if Fingerprint(t) == fp {
+			m.transactionsTable.SetCursor(i)
+			break
+		}
+	}
+	stmt := m.statements[m.selectedStmtIdx]
+	m.status = fmt.Sprintf("Statement %s/%s", stmt.StmtYr, stmt.StmtMo)
+
+	return m
+}
+
 func (m model) View() string {
 	if !m.ready {
 		return "Loading..."
 	}
 
 	var content string
-	if m.currentView == summaryView {
+	switch m.currentView {
+	case summaryView:
 		content = m.renderSummaryView()
-	} else {
+	case merchantsView:
+		content = m.renderMerchantsView()
+	default:
 		content = m.renderStatementsView()
 	}
 
@@ -643,10 +826,36 @@ func (m model) renderHelp() string {
 		Padding(1, 0)
 
 	var helpText string
-	if m.currentView == summaryView {
+	switch {
+	case m.editing != editNone:
+		prompt := map[editMode]string{editTags: "Tags", editNote: "Note", editTagFilter: "Filter by tag", editSearch: "Search", editExport: "Export to (.csv or .json)"}[m.editing]
+		promptStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("86"))
+		keys := "Enter: Save | Esc: Cancel | Ctrl+U: Clear"
+		if m.editing == editSearch {
+			hint := "e.g. category:Food amount>500 currency:EUR date:2025-03..2025-05 card:1234 -desc:UBER"
+			if m.searchErr != "" {
+				hint = lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Render(m.searchErr)
+			}
+			keys = hint + " | Enter: Browse Results | Esc: Clear Search | Ctrl+U: Clear"
+		}
+		return helpStyle.Render(promptStyle.Render(prompt+": ") + m.input + "█\n" + keys)
+	case m.picking:
+		helpText = "↑/↓: Choose Category | Enter: Apply | Esc: Cancel"
+	case m.showDetail:
+		helpText = "Esc/Enter: Close | c: Set Category | t: Tags | n: Note | q: Close"
+	case m.currentView == summaryView:
 		helpText = "Tab: Switch View | q: Quit"
-	} else {
-		helpText = "Tab: Switch View | ←/→: Switch Panel | ↑/↓/PgUp/PgDn/Home/End: Navigate | 1-7: Filter | s: Sort | q: Quit"
+	case m.currentView == merchantsView:
+		helpText = "Tab: Switch View | ←/→/Enter: Switch Panel | ↑/↓/PgUp/PgDn/Home/End: Navigate | s: Rank by | q: Quit"
+	case m.search != "":
+		helpText = "Tab: Switch View | ↑/↓/PgUp/PgDn/Home/End: Navigate | 1-9, [/]: Filter | #: Tag Filter | s: Sort | q: Quit\n" +
+			"Enter: Go to Statement | /: Edit Search | Esc: Clear Search | c: Set Category | t: Edit Tags | n: Edit Note | e/E: Export Results/All"
+	default:
+		helpText = "Tab: Switch View | ←/→: Switch Panel | ↑/↓/PgUp/PgDn/Home/End: Navigate | 1-9, [/]: Filter | #: Tag Filter | s: Sort | q: Quit\n" +
+			"Enter: Details | /: Search | c: Set Category | t: Edit Tags | n: Edit Note | e/E: Export Statement/All"
+	}
+	if m.status != "" {
+		helpText = m.status + "\n" + helpText
 	}
 
 	return helpStyle.Render(helpText)
@@ -665,50 +874,27 @@ func (m model) renderSummaryView() string {
 
 	var b strings.Builder
 
+	summary := BuildSummary(m.statements, m.categorized, m.categories)
+
 	b.WriteString(titleStyle.Render("📊 Transaction Analysis Summary"))
 	b.WriteString("\n\n")
 
-	b.WriteString(fmt.Sprintf("Total Statements: %d\n", len(m.statements)))
-	b.WriteString(fmt.Sprintf("Apple Pay Transactions: %d\n", len(m.categorized.ApplePay)))
-	b.WriteString(fmt.Sprintf("PayPal Transactions: %d\n", len(m.categorized.PayPal)))
-	b.WriteString(fmt.Sprintf("LINE Pay Transactions: %d\n", len(m.categorized.LinePay)))
-	b.WriteString(fmt.Sprintf("Jkopay Transactions: %d\n", len(m.categorized.Jkopay)))
-	b.WriteString(fmt.Sprintf("Foreign Transaction Fees: %d\n", len(m.categorized.ForeignFees)))
-	b.WriteString(fmt.Sprintf("Other Transactions: %d\n", len(m.categorized.Other)))
+	b.WriteString(fmt.Sprintf("Total Statements: %d\n", summary.Statements))
+	b.WriteString(fmt.Sprintf("Apple Pay Transactions: %d\n", summary.ApplePay.Count))
+	b.WriteString(fmt.Sprintf("PayPal Transactions: %d\n", summary.PayPal.Count))
+	b.WriteString(fmt.Sprintf("LINE Pay Transactions: %d\n", summary.LinePay.Count))
+	b.WriteString(fmt.Sprintf("Jkopay Transactions: %d\n", summary.Jkopay.Count))
+	b.WriteString(fmt.Sprintf("Foreign Transaction Fees: %d\n", summary.ForeignFees.Count))
+	b.WriteString(fmt.Sprintf("Other Transactions: %d\n", summary.Other.Count))
 
 	// Apple Pay breakdown
 	b.WriteString("\n")
 	b.WriteString(sectionStyle.Render("🍎 Apple Pay Breakdown"))
 	b.WriteString("\n")
 
-	applePayByCard := make(map[string][]Transaction)
-	for _, tx := range m.categorized.ApplePay {
-		cardKey := tx.ApplePayCardLast4
-		if cardKey == "" {
-			cardKey = "Unknown"
-		}
-		applePayByCard[cardKey] = append(applePayByCard[cardKey], tx)
-	}
-
-	// Sort card keys
-	cardKeys := make([]string, 0, len(applePayByCard))
-	for k := range applePayByCard {
-		cardKeys = append(cardKeys, k)
-	}
-	sort.Strings(cardKeys)
-
-	for _, cardLast4 := range cardKeys {
-		txs := applePayByCard[cardLast4]
-		total := 0.0
-		for _, tx := range txs {
-			amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-			if amt == 0 {
-				amt, _ = strconv.ParseFloat(tx.Amount, 64)
-			}
-			total += amt
-		}
-		b.WriteString(fmt.Sprintf("  Card ending in %s: %d transactions, Total: NT$%s\n",
-			cardLast4, len(txs), formatAmount(total)))
+	for _, card := range summary.ApplePayCards {
+		b.WriteString(fmt.Sprintf("  Card ending in %s: %d transactions, Total: %s\n",
+			card.Card, card.Count, card.Totals))
 	}
 
 	// PayPal summary
@@ -716,64 +902,64 @@ func (m model) renderSummaryView() string {
 	b.WriteString(sectionStyle.Render("💳 PayPal Summary"))
 	b.WriteString("\n")
 
-	paypalTotal := 0.0
-	for _, tx := range m.categorized.PayPal {
-		amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-		if amt == 0 {
-			amt, _ = strconv.ParseFloat(tx.Amount, 64)
-		}
-		paypalTotal += amt
-	}
-	b.WriteString(fmt.Sprintf("  Total PayPal Transactions: %d\n", len(m.categorized.PayPal)))
-	b.WriteString(fmt.Sprintf("  Total PayPal Amount: NT$%s\n", formatAmount(paypalTotal)))
+	b.WriteString(fmt.Sprintf("  Total PayPal Transactions: %d\n", summary.PayPal.Count))
+	b.WriteString(fmt.Sprintf("  Total PayPal Amount: %s\n", summary.PayPal.Totals))
 
 	// LINE Pay summary
 	b.WriteString("\n")
 	b.WriteString(sectionStyle.Render("💚 LINE Pay Summary"))
 	b.WriteString("\n")
 
-	linePayTotal := 0.0
-	for _, tx := range m.categorized.LinePay {
-		amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-		if amt == 0 {
-			amt, _ = strconv.ParseFloat(tx.Amount, 64)
-		}
-		linePayTotal += amt
-	}
-	b.WriteString(fmt.Sprintf("  Total LINE Pay Transactions: %d\n", len(m.categorized.LinePay)))
-	b.WriteString(fmt.Sprintf("  Total LINE Pay Amount: NT$%s\n", formatAmount(linePayTotal)))
+	b.WriteString(fmt.Sprintf("  Total LINE Pay Transactions: %d\n", summary.LinePay.Count))
+	b.WriteString(fmt.Sprintf("  Total LINE Pay Amount: %s\n", summary.LinePay.Totals))
 
 	// Jkopay summary
 	b.WriteString("\n")
 	b.WriteString(sectionStyle.Render("🏪 Jkopay Summary"))
 	b.WriteString("\n")
 
-	jkopayTotal := 0.0
-	for _, tx := range m.categorized.Jkopay {
-		amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-		if amt == 0 {
-			amt, _ = strconv.ParseFloat(tx.Amount, 64)
-		}
-		jkopayTotal += amt
-	}
-	b.WriteString(fmt.Sprintf("  Total Jkopay Transactions: %d\n", len(m.categorized.Jkopay)))
-	b.WriteString(fmt.Sprintf("  Total Jkopay Amount: NT$%s\n", formatAmount(jkopayTotal)))
+	b.WriteString(fmt.Sprintf("  Total Jkopay Transactions: %d\n", summary.Jkopay.Count))
+	b.WriteString(fmt.Sprintf("  Total Jkopay Amount: %s\n", summary.Jkopay.Totals))
 
 	// Foreign fees summary
 	b.WriteString("\n")
 	b.WriteString(sectionStyle.Render("🌍 Foreign Transaction Fees Summary"))
 	b.WriteString("\n")
 
-	feeTotal := 0.0
-	for _, tx := range m.categorized.ForeignFees {
-		amt, _ := strconv.ParseFloat(tx.NtdAmount, 64)
-		if amt == 0 {
-			amt, _ = strconv.ParseFloat(tx.Amount, 64)
+	b.WriteString(fmt.Sprintf("  Total Foreign Fees: %d\n", summary.ForeignFees.Count))
+	b.WriteString(fmt.Sprintf("  Total Fee Amount: %s\n", summary.ForeignFees.Totals))
+
+	// Spending per category, with parents including their subcategories
+	b.WriteString("\n")
+	b.WriteString(sectionStyle.Render("🗂  Spending by Category"))
+	b.WriteString("\n")
+
+	for _, total := range summary.Categories {
+		indent := strings.Repeat("  ", CategoryDepth(total.Category)+1)
+		b.WriteString(fmt.Sprintf("%s%s: %d transactions, Total: %s\n",
+			indent, m.categoryLabel(total.Category), total.Count, total.Totals))
+	}
+
+	// Totals per tag, counting every transaction that carries it
+	if len(summary.Tags) > 0 {
+		b.WriteString("\n")
+		b.WriteString(sectionStyle.Render("🏷  Tags"))
+		b.WriteString("\n")
+
+		for _, total := range summary.Tags {
+			b.WriteString(fmt.Sprintf("  #%s: %d transactions, Total: %s\n", total.Tag, total.Count, total.Totals))
 		}
-		feeTotal += amt
 	}
-	b.WriteString(fmt.Sprintf("  Total Foreign Fees: %d\n", len(m.categorized.ForeignFees)))
-	b.WriteString(fmt.Sprintf("  Total Fee Amount: NT$%s\n", formatAmount(feeTotal)))
+
+	// Busiest days by number of purchases
+	b.WriteString("\n")
+	b.WriteString(sectionStyle.Render("📆 Busiest Days"))
+	b.WriteString("\n")
+
+	for _, day := range summary.BusiestDays {
+		b.WriteString(fmt.Sprintf("  %s: %d transactions, Total: %s\n",
+			day.Date.Format(displayDateLayout), day.Count, day.Totals))
+	}
 
 	return b.String()
 }
@@ -788,17 +974,7 @@ func (m model) renderStatementsView() string {
 		Bold(true).
 		Foreground(lipgloss.Color("86"))
 
-	var sortLabel string
-	switch m.sortBy {
-	case sortByDate:
-		sortLabel = "Date"
-	case sortByAmount:
-		sortLabel = "Amount"
-	case sortByLocation:
-		sortLabel = "Location"
-	case sortByCategory:
-		sortLabel = "Category"
-	}
+	sortLabel := sortLabels[m.sortBy]
 
 	// Determine border colors based on focus
 	leftBorderColor := lipgloss.Color("240") // Dim gray
@@ -829,7 +1005,7 @@ func (m model) renderStatementsView() string {
 	leftHeader := headerStyle.Render("📅 Statements") + stmtScrollInfo
 	leftPanelBox := lipgloss.NewStyle().
 		Width(38).
-		Height(m.height - 8).
+		Height(m.height - 9).
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(leftBorderColor).
 		Padding(1).
@@ -846,41 +1022,58 @@ func (m model) renderStatementsView() string {
 		Foreground(lipgloss.Color("240")).
 		Padding(0, 1)
 
-	tabs := []struct {
-		key   string
-		label string
-		cat   string
-	}{
-		{"1", "All", CategoryAll},
-		{"2", "Food", CategoryFood},
-		{"3", "Transport", CategoryTransport},
-		{"4", "Shopping", CategoryShopping},
-		{"5", "Travel", CategoryTravel},
-		{"6", "Utilities", CategoryUtilities},
-		{"7", "Other", CategoryOther},
+	tabs := m.categoryTabs()
+	end := m.tabOffset + maxCategoryTabs
+	if end > len(tabs) {
+		end = len(tabs)
 	}
 
 	var tabBar strings.Builder
-	for i, tab := range tabs {
+	if m.tabOffset > 0 {
+		tabBar.WriteString(inactiveTabStyle.Render("‹"))
+	}
+	for i, cat := range tabs[m.tabOffset:end] {
 		if i > 0 {
 			tabBar.WriteString(" ")
 		}
 		tabStyle := inactiveTabStyle
-		if m.categoryFilter == tab.cat {
+		if cat != CategoryAll {
+			tabStyle = tabStyle.Foreground(lipgloss.Color(m.categories.Color(cat)))
+		}
+		if m.filter.Category() == cat {
 			tabStyle = activeTabStyle
+			if cat != CategoryAll {
+				tabStyle = tabStyle.Background(lipgloss.Color(m.categories.Color(cat)))
+			}
 		}
-		tabBar.WriteString(tabStyle.Render(fmt.Sprintf("%s:%s", tab.key, tab.label)))
+		tabBar.WriteString(tabStyle.Render(fmt.Sprintf("%d:%s", i+1, m.categoryLabel(cat))))
+	}
+	if end < len(tabs) {
+		tabBar.WriteString(inactiveTabStyle.Render("›"))
 	}
 
-	// Render right panel with transactions table
-	rightHeader := headerStyle.Render(fmt.Sprintf("💰 Transactions (Sort by: %s)", sortLabel)) + txScrollInfo
+	// Render right panel with transactions table, or the category picker
+	rightHeader := headerStyle.Render(fmt.Sprintf("💰 Transactions (Sort by: %s)", sortLabel))
+	if m.search != "" {
+		rightHeader = headerStyle.Render(fmt.Sprintf("🔍 %q in all statements (Sort by: %s)", m.search, sortLabel))
+	}
+	if tag := m.filter.Tag(); tag != "" {
+		rightHeader += headerStyle.Render(" #" + tag)
+	}
+	rightHeader += txScrollInfo
+	rightBody := m.transactionsTable.View()
+	if m.picking {
+		rightBody = m.renderPicker()
+	} else if m.showDetail {
+		rightBody = m.renderDetail()
+	}
 	rightPanelBox := lipgloss.NewStyle().
 		Width(m.width - 42).
-		Height(m.height - 8).
+		Height(m.height - 9).
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(rightBorderColor).
 		Padding(1).
-		Render(rightHeader + "\n" + tabBar.String() + "\n" + m.transactionsTable.View())
+		Render(rightHeader + "\n" + tabBar.String() + "\n" + rightBody)
 
 	// Combine panels
 	content := lipgloss.JoinHorizontal(lipgloss.Top, leftPanelBox, rightPanelBox)
@@ -889,54 +1082,413 @@ func (m model) renderStatementsView() string {
 	return lipgloss.JoinVertical(lipgloss.Left, title, content)
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// updateMerchantsTable ranks the merchants and rebuilds the leaderboard
+func (m model) updateMerchantsTable() model {
+	m.merchantTotals = MerchantTotals(m.statements)
+	switch m.merchantRank {
+	case rankByVisits:
+		sort.SliceStable(m.merchantTotals, func(i, j int) bool {
+			return m.merchantTotals[i].Visits > m.merchantTotals[j].Visits
+		})
+	case rankByAverage:
+		sort.SliceStable(m.merchantTotals, func(i, j int) bool {
+			return m.merchantTotals[i].Average().Cmp(m.merchantTotals[j].Average()) > 0
+		})
 	}
-	return s[:maxLen-3] + "..."
+
+	rows := make([]table.Row, len(m.merchantTotals))
+	for i, total := range m.merchantTotals {
+		rows[i] = table.Row{
+			fmt.Sprintf("%d", i+1),
+			truncate(total.Merchant, 22),
+			fmt.Sprintf("%6d", total.Visits),
+			rightPadAmount(total.Total.Display(), 16),
+			rightPadAmount(total.Average().Display(), 14),
+		}
+	}
+	m.merchantsTable.SetRows(rows)
+	m.merchantsTable.GotoTop()
+	m.selectedMerchant = 0
+
+	return m.updateMerchantTxTable()
 }
 
-// formatAmount formats a number with comma separators
-func formatAmount(amount float64) string {
-	// Format with 2 decimal places
-	str := fmt.Sprintf("%.2f", amount)
+// updateMerchantTxTable lists every transaction of the selected merchant
+// across all statements
+func (m model) updateMerchantTxTable() model {
+	var rows []table.Row
+	if m.selectedMerchant < len(m.merchantTotals) {
+		for _, tx := range m.merchantTotals[m.selectedMerchant].Transactions {
+			rows = append(rows, table.Row{
+				formatDate(tx.TransactionDate, tx.TxnDate),
+				m.categoryCell(tx),
+				rightPadAmount(tx.BilledAmount.Format(), 13),
+				truncate(GetCleanDescription(tx.NormalizedDescription), 22),
+				truncate(tx.TxnLoc, 8),
+			})
+		}
+	}
+	m.merchantTxTable.SetRows(rows)
+	m.merchantTxTable.GotoTop()
+	return m
+}
 
-	// Split into integer and decimal parts
-	parts := strings.Split(str, ".")
-	intPart := parts[0]
-	decPart := parts[1]
+// renderMerchantsView shows the merchant leaderboard next to the
+// transactions of the selected merchant
+func (m model) renderMerchantsView() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
 
-	// Handle negative sign
-	negative := false
-	if strings.HasPrefix(intPart, "-") {
-		negative = true
-		intPart = intPart[1:] // Remove the minus sign
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("86"))
+
+	rankLabel := map[merchantSortMode]string{rankBySpend: "Total Spend", rankByVisits: "Visits", rankByAverage: "Average Ticket"}[m.merchantRank]
+
+	leftBorderColor := lipgloss.Color("240")
+	rightBorderColor := lipgloss.Color("240")
+	if m.focusedTable == 0 {
+		leftBorderColor = lipgloss.Color("86")
+	} else {
+		rightBorderColor = lipgloss.Color("86")
+	}
+
+	leftHeader := headerStyle.Render(fmt.Sprintf("🏆 Merchants (Rank by: %s)", rankLabel))
+	leftPanelBox := lipgloss.NewStyle().
+		Width(76).
+		Height(m.height - 9).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(leftBorderColor).
+		Padding(1).
+		Render(leftHeader + "\n\n" + m.merchantsTable.View())
+
+	rightHeader := headerStyle.Render("🧾 Transactions")
+	if m.selectedMerchant < len(m.merchantTotals) {
+		total := m.merchantTotals[m.selectedMerchant]
+		rightHeader = headerStyle.Render(fmt.Sprintf("🧾 %s: %d visits, %s", total.Merchant, total.Visits, total.Total.Display()))
+	}
+	rightPanelBox := lipgloss.NewStyle().
+		Width(m.width - 80).
+		Height(m.height - 9).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(rightBorderColor).
+		Padding(1).
+		Render(rightHeader + "\n\n" + m.merchantTxTable.View())
+
+	content := lipgloss.JoinHorizontal(lipgloss.Top, leftPanelBox, rightPanelBox)
+	title := titleStyle.Render("🏪 Merchant Leaderboard")
+	return lipgloss.JoinVertical(lipgloss.Left, title, content)
+}
+
+// maxCategoryTabs is the number of category tabs reachable with keys 1-9
+const maxCategoryTabs = 9
+
+// categoryTabs lists the filter tabs: All followed by every category
+func (m model) categoryTabs() []string {
+	return append([]string{CategoryAll}, m.categories.Names()...)
+}
+
+// categoryLabel returns the display name of a category filter
+func (m model) categoryLabel(cat string) string {
+	if cat == CategoryAll {
+		return "All"
+	}
+	return m.categories.Label(cat)
+}
+
+// pickerOptions lists the picker entries: automatic categorization
+// followed by every category
+func (m model) pickerOptions() []string {
+	return append([]string{""}, m.categories.Names()...)
+}
+
+// selectedTransaction returns the transaction under the table cursor. The
+// aggregated foreign fee row has no transaction behind it.
+func (m model) selectedTransaction() (Transaction, bool) {
+	cursor := m.transactionsTable.Cursor()
+	if cursor < 0 || cursor >= len(m.visibleTxs) {
+		return Transaction{}, false
 	}
+	return m.visibleTxs[cursor], true
+}
 
-	// Add commas to integer part
-	var result []rune
-	for i, r := range intPart {
-		if i > 0 && (len(intPart)-i)%3 == 0 {
-			result = append(result, ',')
+// updatePicker handles keys while the category picker is open
+func (m model) updatePicker(msg tea.KeyMsg) model {
+	options := m.pickerOptions()
+
+	switch msg.String() {
+	case "esc", "q":
+		m.picking = false
+	case "up", "k":
+		m.pickerCursor = (m.pickerCursor + len(options) - 1) % len(options)
+	case "down", "j":
+		m.pickerCursor = (m.pickerCursor + 1) % len(options)
+	case "enter":
+		m.picking = false
+		if tx, ok := m.selectedTransaction(); ok {
+			m = m.overrideCategory(tx, options[m.pickerCursor])
 		}
-		result = append(result, r)
 	}
 
-	formatted := string(result) + "." + decPart
-	if negative {
-		formatted = "-" + formatted
+	return m
+}
+
+// overrideCategory assigns a category to every transaction sharing the
+// fingerprint of tx and saves the override. An empty category removes the
+// override and restores the rule-based category.
+func (m model) overrideCategory(tx Transaction, category string) model {
+	m.overrides.SetCategory(tx, category)
+
+	done := fmt.Sprintf("Saved %s as %s", GetCleanDescription(tx.NormalizedDescription), category)
+	if category == "" {
+		done = "Category override removed"
 	}
 
-	return formatted
+	return m.annotate(tx, func(t *Transaction) {
+		if category == "" {
+			m.categorizer.Categorize(t, GetCleanDescription(t.NormalizedDescription))
+		} else {
+			t.Category = category
+			t.Confidence = 0
+		}
+	}, done)
 }
 
-// formatAmountString parses a string and formats it with commas
-func formatAmountString(amountStr string) string {
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil {
-		return amountStr
+// annotate applies a change already recorded in the override store to
+// every transaction sharing the fingerprint of tx, saves the store and
+// rebuilds the summary groups and the table in place
+func (m model) annotate(tx Transaction, change func(t *Transaction), done string) model {
+	fp := Fingerprint(tx)
+	for i := range m.statements {
+		for j := range m.statements[i].Transactions {
+			if t := &m.statements[i].Transactions[j]; Fingerprint(*t) == fp {
+				change(t)
+			}
+		}
 	}
-	return formatAmount(amount)
+
+	m.categorized = GroupTransactions(m.statements)
+
+	if err := m.overrides.Save(); err != nil {
+		m.status = fmt.Sprintf("Could not save overrides: %v", err)
+	} else {
+		m.status = done
+	}
+
+	// Rebuild the tables and put the cursor back where it was
+	cursor := m.transactionsTable.Cursor()
+	m = m.updateMerchantsTable()
+	m = m.updateTransactionsTable()
+	rowCount := len(m.transactionsTable.Rows())
+	for i := 0; i < cursor && i < rowCount-1; i++ {
+		m.transactionsTable.MoveDown(1)
+	}
+
+	return m
+}
+
+// updateEditor handles keys while the input line is open
+func (m model) updateEditor(msg tea.KeyMsg) model {
+	switch msg.Type {
+	case tea.KeyEsc:
+		if m.editing == editSearch {
+			m.search, m.searchQuery, m.searchErr = "", Query{}, ""
+			m = m.updateTransactionsTable()
+		}
+		m.editing = editNone
+		return m
+	case tea.KeyEnter:
+		m = m.commitEdit()
+		m.editing = editNone
+		return m
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.input = ""
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+
+	// Search results follow the query as it is typed, keeping the last
+	// results while the query does not parse
+	if m.editing == editSearch {
+		text := strings.TrimSpace(m.input)
+		query, err := ParseQuery(text)
+		m.searchErr = ""
+		if err != nil {
+			m.searchErr = err.Error()
+		} else if text != m.search {
+			m.search, m.searchQuery = text, query
+			m = m.updateTransactionsTable()
+		}
+	}
+	return m
+}
+
+// commitEdit saves what was typed into the input line
+func (m model) commitEdit() model {
+	if m.editing == editSearch {
+		// Already applied while typing
+		return m
+	}
+	if m.editing == editExport {
+		return m.export(strings.TrimSpace(m.input))
+	}
+	if m.editing == editTagFilter {
+		tag := ""
+		if tags := ParseTags(m.input); len(tags) > 0 {
+			tag = tags[0]
+		}
+		m.filter = m.filter.WithTag(tag)
+		return m.updateTransactionsTable()
+	}
+
+	tx, ok := m.selectedTransaction()
+	if !ok {
+		return m
+	}
+	desc := GetCleanDescription(tx.NormalizedDescription)
+
+	if m.editing == editTags {
+		tags := ParseTags(m.input)
+		m.overrides.SetTags(tx, tags)
+		return m.annotate(tx, func(t *Transaction) { t.Tags = tags }, fmt.Sprintf("Tagged %s %s", desc, FormatTags(tags)))
+	}
+
+	note := strings.TrimSpace(m.input)
+	m.overrides.SetNote(tx, note)
+	return m.annotate(tx, func(t *Transaction) { t.Note = note }, fmt.Sprintf("Saved note on %s", desc))
+}
+
+// export writes the table rows, or those of every statement, to a file
+func (m model) export(path string) model {
+	if path == "" || path == "-" {
+		m.status = "Export needs a file name"
+		return m
+	}
+
+	rows := m.rows
+	if m.exportAll {
+		rows = nil
+		for i := range m.statements {
+			rows = append(rows, StatementRows(m.statements, i, m.filter, m.sortBy)...)
+		}
+	}
+
+	if err := ExportFile(path, "", rows); err != nil {
+		m.status = fmt.Sprintf("Could not export: %v", err)
+	} else {
+		m.status = fmt.Sprintf("Exported %d rows to %s", len(rows), path)
+	}
+	return m
+}
+
+// renderPicker lists the categories that can be assigned to the selected
+// transaction
+func (m model) renderPicker() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	cursorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("230")).
+		Background(lipgloss.Color("62"))
+
+	tx, _ := m.selectedTransaction()
+	current := "automatic"
+	if _, ok := m.overrides.Get(tx); ok {
+		current = "override"
+	} else if tx.Confidence > 0 {
+		current = fmt.Sprintf("suggested, %.0f%% confidence", tx.Confidence*100)
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Category for %s (%s, %s)",
+		truncate(GetCleanDescription(tx.NormalizedDescription), 40), m.categoryLabel(tx.Category), current)))
+	b.WriteString("\n")
+
+	for i, cat := range m.pickerOptions() {
+		label := "(automatic)"
+		style := lipgloss.NewStyle()
+		if cat != "" {
+			label = strings.Repeat("  ", CategoryDepth(cat)) + m.categoryLabel(cat)
+			style = style.Foreground(lipgloss.Color(m.categories.Color(cat)))
+		}
+		if i == m.pickerCursor {
+			style = cursorStyle
+		}
+		b.WriteString("  " + style.Render(label) + "\n")
+	}
+
+	return b.String()
+}
+
+// renderDetail shows every field of the selected transaction and how its
+// category was decided
+func (m model) renderDetail() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	tx, _ := m.selectedTransaction()
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(GetCleanDescription(tx.NormalizedDescription)))
+	b.WriteString("\n")
+
+	field := func(label, value string) {
+		if value != "" {
+			b.WriteString(labelStyle.Render(fmt.Sprintf("%-10s", label)) + value + "\n")
+		}
+	}
+	field("Merchant", tx.Merchant)
+	field("Date", formatDate(tx.TransactionDate, tx.TxnDate))
+	field("Posted", formatDate(tx.PostedDate, tx.PostingDate))
+	field("Billed", tx.BilledAmount.Display())
+	if tx.OriginalAmount.Currency != tx.BilledAmount.Currency {
+		field("Original", tx.OriginalAmount.Display())
+	}
+	field("Location", tx.TxnLoc)
+	field("Card", tx.CardNo)
+	field("Category", tx.Category)
+	field("Tags", FormatTags(tx.Tags))
+	field("Note", tx.Note)
+
+	b.WriteString("\n")
+	b.WriteString(titleStyle.Render("Why this category"))
+	b.WriteString("\n")
+	b.WriteString(m.categorizer.Explain(tx, m.overrides).String())
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// categoryCell renders the category column, marking classifier
+// suggestions with their confidence
+func (m model) categoryCell(tx Transaction) string {
+	label := m.categories.Label(tx.Category)
+	if tx.Confidence == 0 {
+		return truncate(label, 14)
+	}
+	return fmt.Sprintf("%s ~%.0f%%", truncate(label, 9), tx.Confidence*100)
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// billedTotals sums the billed amounts of transactions per currency
+func billedTotals(txs []Transaction) Totals {
+	totals := Totals{}
+	for _, tx := range txs {
+		totals.Add(tx.BilledAmount)
+	}
+	return totals
 }
 
 // rightPadAmount right-aligns an amount within a fixed width
@@ -949,25 +1501,44 @@ func rightPadAmount(amountStr string, width int) string {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: statements <statementlist.json>")
-		os.Exit(1)
+	// Subcommands take over the rest of the command line
+	if len(os.Args) > 1 {
+		if c, ok := findCommand(os.Args[1]); ok {
+			if err := c.run(c, os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	filename := os.Args[1]
+	var opts options
+	opts.register(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: statements [flags] <statementlist.json|directory|glob>...")
+		fmt.Fprintln(flag.CommandLine.Output(), "       statements <command> [flags] <inputs>...")
+		printCommands()
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	// Load statements
-	statements, err := LoadStatements(filename)
-	if err != nil {
-		fmt.Printf("Error loading statements: %v\n", err)
+	if flag.NArg() < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Categorize transactions
-	categorized := CategorizeTransactions(statements)
+	s, err := loadSession(opts, flag.Args(), true)
+	if err != nil {
+		fmt.Printf("Error %v\n", err)
+		os.Exit(1)
+	}
+	if s.report.HasIssues() {
+		fmt.Fprint(os.Stderr, s.report.String())
+	}
 
 	// Initialize bubbletea program
-	p := tea.NewProgram(initialModel(statements, categorized), tea.WithAltScreen())
+	p := tea.NewProgram(initialModel(s.statements, s.categorized, s.categories, s.categorizer, s.overrides), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MerchantAlias maps descriptions matching a pattern to a canonical
+// merchant name. Patterns work like those of categorization rules.
+type MerchantAlias struct {
+	Match    string `json:"match"`   // prefix, contains, regex or exact
+	Pattern  string `json:"pattern"` // Text or regular expression to match
+	Merchant string `json:"merchant"`
+
+	re *regexp.Regexp
+}
+
+// merchantsFile is the on-disk layout of a merchant aliases file
+type merchantsFile struct {
+	Aliases []MerchantAlias `json:"aliases"`
+}
+
+// compile validates an alias and prepares its pattern
+func (a *MerchantAlias) compile() error {
+	a.Merchant = strings.TrimSpace(a.Merchant)
+	if a.Merchant == "" {
+		return errors.New("alias has no merchant")
+	}
+	if a.Pattern == "" {
+		return errors.New("alias has no pattern")
+	}
+
+	switch a.Match {
+	case MatchPrefix, MatchContains, MatchExact:
+		a.Pattern = strings.ToUpper(a.Pattern)
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + a.Pattern)
+		if err != nil {
+			return err
+		}
+		a.re = re
+	default:
+		return fmt.Errorf("unknown match type %q", a.Match)
+	}
+	return nil
+}
+
+// matches reports whether the alias applies to a description
+func (a MerchantAlias) matches(desc string) bool {
+	desc = strings.ToUpper(strings.TrimSpace(desc))
+	switch a.Match {
+	case MatchPrefix:
+		return strings.HasPrefix(desc, a.Pattern)
+	case MatchContains:
+		return strings.Contains(desc, a.Pattern)
+	case MatchExact:
+		return desc == a.Pattern
+	case MatchRegex:
+		return a.re.MatchString(desc)
+	}
+	return false
+}
+
+// processorPrefixes are payment processors that put their name in front of
+// the merchant's
+var processorPrefixes = []string{"SQ *", "SQ*", "PAYPAL *", "PAYPAL*", "PP*", "SP *", "SP*", "ZETTLE_*", "SUMUP *"}
+
+// storeNumber matches store and terminal numbers such as "#1234", "NO.12"
+// or a bare number of two or more digits
+var storeNumber = regexp.MustCompile(`^(#\d+|NO\.?\d+|\d{2,})$`)
+
+// defaultAliases returns the built-in aliases for merchants whose
+// descriptions vary from one transaction to the next
+func defaultAliases() []MerchantAlias {
+	aliases := []MerchantAlias{
+		{Match: MatchPrefix, Pattern: "UBR*", Merchant: "UBER"},
+		{Match: MatchPrefix, Pattern: "UBER", Merchant: "UBER"},
+		{Match: MatchContains, Pattern: "UBER.COM", Merchant: "UBER"},
+		{Match: MatchPrefix, Pattern: "AMZN", Merchant: "AMAZON"},
+		{Match: MatchPrefix, Pattern: "AMAZON", Merchant: "AMAZON"},
+		{Match: MatchPrefix, Pattern: "WWW.AMAZON", Merchant: "AMAZON"},
+		{Match: MatchPrefix, Pattern: "APPLE.COM", Merchant: "APPLE"},
+		{Match: MatchPrefix, Pattern: "GOOGLE", Merchant: "GOOGLE"},
+	}
+	for i := range aliases {
+		if err := aliases[i].compile(); err != nil {
+			panic(err)
+		}
+	}
+	return aliases
+}
+
+// LoadMerchantAliases reads merchant aliases from the explicit file, or
+// merchants.json in the configuration directory when it exists
+func LoadMerchantAliases(explicit string) ([]MerchantAlias, error) {
+	path := explicit
+	if path == "" {
+		path = configPath("merchants.json")
+		if path == "" {
+			return nil, nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file merchantsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range file.Aliases {
+		if err := file.Aliases[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: alias %d: %w", path, i+1, err)
+		}
+	}
+
+	return file.Aliases, nil
+}
+
+// MerchantResolver maps transaction descriptions to canonical merchant
+// names using user aliases, the built-in aliases and built-in stripping
+type MerchantResolver struct {
+	Aliases []MerchantAlias
+}
+
+// NewMerchantResolver builds a resolver where user aliases take precedence
+// over the built-in ones
+func NewMerchantResolver(userAliases []MerchantAlias) *MerchantResolver {
+	aliases := make([]MerchantAlias, 0, len(userAliases))
+	aliases = append(aliases, userAliases...)
+	aliases = append(aliases, defaultAliases()...)
+	return &MerchantResolver{Aliases: aliases}
+}
+
+// Resolve returns the merchant of a transaction. Aliases are tried on the
+// clean description and on the stripped one; without a match the stripped
+// description is the merchant.
+func (r *MerchantResolver) Resolve(tx *Transaction, cleanDesc string) string {
+	stripped := StripMerchant(cleanDesc, tx.TxnLoc)
+	for _, alias := range r.Aliases {
+		if alias.matches(cleanDesc) || alias.matches(stripped) {
+			return alias.Merchant
+		}
+	}
+	if stripped == "" {
+		return strings.ToUpper(strings.TrimSpace(cleanDesc))
+	}
+	return stripped
+}
+
+// StripMerchant reduces a description to the merchant name: it removes
+// payment processor prefixes, store numbers, the transaction's city and
+// repeated whitespace
+func StripMerchant(desc, location string) string {
+	s := strings.ToUpper(strings.Join(strings.Fields(desc), " "))
+
+	// Payment processor prefixes
+	for _, prefix := range processorPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			s = strings.TrimSpace(s[len(prefix):])
+			break
+		}
+	}
+
+	// Processor reference numbers at the start, store numbers and the
+	// city at the end
+	words := strings.Fields(s)
+	for len(words) > 1 && storeNumber.MatchString(words[0]) {
+		words = words[1:]
+	}
+	city := strings.Fields(strings.ToUpper(location))
+	for len(words) > 1 {
+		if storeNumber.MatchString(words[len(words)-1]) {
+			words = words[:len(words)-1]
+			continue
+		}
+		if n := len(city); n > 0 && len(words) > n && strings.Join(words[len(words)-n:], " ") == strings.Join(city, " ") {
+			words = words[:len(words)-n]
+			continue
+		}
+		break
+	}
+	return strings.Trim(strings.Join(words, " "), " -*.,")
+}
+
+// ResolveMerchants sets the Merchant of every transaction
+func ResolveMerchants(statements []Statement, resolver *MerchantResolver) {
+	for i := range statements {
+		for j := range statements[i].Transactions {
+			tx := &statements[i].Transactions[j]
+			tx.Merchant = resolver.Resolve(tx, GetCleanDescription(tx.NormalizedDescription))
+		}
+	}
+}
+
+// MerchantTotal is the spend at one merchant in one currency
+type MerchantTotal struct {
+	Merchant     string
+	Currency     string
+	Visits       int
+	Total        Money
+	Transactions []Transaction // Newest first
+}
+
+// Average returns the average ticket, truncated to the smallest unit
+func (t MerchantTotal) Average() Money {
+	if t.Visits == 0 {
+		return Money{Currency: t.Currency}
+	}
+	return Money{Units: t.Total.Units / int64(t.Visits), Currency: t.Currency}
+}
+
+// MerchantTotals aggregates purchases per merchant and billed currency
+// across every statement, ordered by total spend. Merchants billed in
+// several currencies get one entry per currency.
+func MerchantTotals(statements []Statement) []MerchantTotal {
+	byMerchant := make(map[[2]string]*MerchantTotal)
+	var order [][2]string
+	for _, stmt := range statements {
+		for _, tx := range stmt.Transactions {
+			// Foreign transaction fees are charged by the bank, not a merchant
+			if tx.BilledAmount.Sign() <= 0 || tx.Merchant == "" || strings.HasPrefix(tx.NormalizedDescription, "國外交易手續費") {
+				continue
+			}
+			key := [2]string{tx.Merchant, tx.BilledAmount.Currency}
+			total, ok := byMerchant[key]
+			if !ok {
+				total = &MerchantTotal{Merchant: tx.Merchant, Currency: tx.BilledAmount.Currency, Total: Money{Currency: tx.BilledAmount.Currency}}
+				byMerchant[key] = total
+				order = append(order, key)
+			}
+			total.Visits++
+			total.Total = total.Total.Add(tx.BilledAmount)
+			total.Transactions = append(total.Transactions, tx)
+		}
+	}
+
+	totals := make([]MerchantTotal, 0, len(order))
+	for _, key := range order {
+		total := byMerchant[key]
+		sort.SliceStable(total.Transactions, func(i, j int) bool {
+			return total.Transactions[i].TransactionDate.After(total.Transactions[j].TransactionDate)
+		})
+		totals = append(totals, *total)
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Total.Cmp(totals[j].Total) > 0 })
+	return totals
+}
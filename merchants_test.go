@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMerchantAliasKeepsMerchantName(t *testing.T) {
+	tests := []struct {
+		alias MerchantAlias
+		desc  string
+		want  string
+	}{
+		{MerchantAlias{Match: MatchPrefix, Pattern: "at&t", Merchant: "AT>T"}, "AT&T WIRELESS", "AT>T"},
+		{MerchantAlias{Match: MatchContains, Pattern: "b&q", Merchant: " Home > Garden "}, "SQ *B&Q LONDON", "Home > Garden"},
+		{MerchantAlias{Match: MatchExact, Pattern: "7-ELEVEN", Merchant: ">"}, "7-eleven", ">"},
+		{MerchantAlias{Match: MatchRegex, Pattern: `^uber\s*eats`, Merchant: "UBER EATS"}, "UBER   EATS", "UBER EATS"},
+	}
+	for _, tt := range tests {
+		alias := tt.alias
+		if err := alias.compile(); err != nil {
+			t.Fatalf("compile(%+v): %v", tt.alias, err)
+		}
+		r := NewMerchantResolver([]MerchantAlias{alias})
+		tx := Transaction{Description: tt.desc}
+		if got := r.Resolve(&tx, tt.desc); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestMerchantAliasCompileErrors(t *testing.T) {
+	for _, alias := range []MerchantAlias{
+		{Match: MatchPrefix, Pattern: "X"},
+		{Match: MatchPrefix, Merchant: "X"},
+		{Match: "suffix", Pattern: "X", Merchant: "X"},
+		{Match: MatchRegex, Pattern: "(", Merchant: "X"},
+	} {
+		if err := alias.compile(); err == nil {
+			t.Errorf("compile(%+v) accepted an invalid alias", alias)
+		}
+	}
+}
+
+func TestMerchantTotals(t *testing.T) {
+	tx := func(merchant string, billed Money, date string) Transaction {
+		tx := journalTx(merchant, 0, date, "")
+		tx.Merchant, tx.BilledAmount = merchant, billed
+		return tx
+	}
+	fee := tx("CAFE", twd(10), "2025-03-04")
+	fee.NormalizedDescription = "國外交易手續費"
+	statements := []Statement{
+		{Transactions: []Transaction{
+			tx("CAFE", twd(100), "2025-03-02"),
+			tx("BOOKS", twd(500), "2025-03-03"),
+			tx("CAFE", twd(150), "2025-03-05"),
+			tx("CAFE", twd(-100), "2025-03-06"),
+			fee,
+		}},
+		{Transactions: []Transaction{
+			tx("CAFE", Money{Units: 45000, Currency: "EUR"}, "2025-04-01"),
+			tx("CAFE", twd(101), "2025-04-02"),
+			tx("", twd(900), "2025-04-03"),
+		}},
+	}
+
+	var got []string
+	for _, total := range MerchantTotals(statements) {
+		got = append(got, fmt.Sprintf("%s %d %s avg %s latest %s",
+			total.Merchant, total.Visits, total.Total.Display(), total.Average().Display(), total.Transactions[0].TxnDate))
+	}
+	want := []string{
+		"BOOKS 1 NT$500.00 avg NT$500.00 latest 2025-03-03",
+		"CAFE 3 NT$351.00 avg NT$117.00 latest 2025-04-02",
+		"CAFE 1 EUR 4.50 avg EUR 4.50 latest 2025-04-01",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("MerchantTotals() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
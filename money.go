@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// moneyScale is the number of Money units per currency unit (4 decimal places)
+const moneyScale = 10000
+
+// Money is an exact fixed-point amount in an ISO 4217 currency
+type Money struct {
+	Units    int64  // Amount in ten-thousandths of the currency unit
+	Currency string // ISO 4217 code, e.g. TWD
+}
+
+// ParseMoney parses a decimal amount such as "1,234.56" or "-12". Commas
+// may only separate groups of three digits. An empty string is a zero
+// amount.
+func ParseMoney(s, currency string) (Money, error) {
+	m := Money{Currency: currency}
+
+	clean := strings.TrimSpace(s)
+	if clean == "" {
+		return m, nil
+	}
+
+	negative := false
+	switch clean[0] {
+	case '-':
+		negative = true
+		clean = clean[1:]
+	case '+':
+		clean = clean[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(clean, ".")
+	if intPart == "" && fracPart == "" {
+		return m, fmt.Errorf("invalid amount %q", s)
+	}
+	if groups := strings.Split(intPart, ","); len(groups) > 1 {
+		for i, g := range groups {
+			if len(g) != 3 && (i > 0 || g == "" || len(g) > 3) {
+				return m, fmt.Errorf("invalid amount %q: misplaced thousands separator", s)
+			}
+		}
+		intPart = strings.Join(groups, "")
+	}
+	if len(fracPart) > 4 {
+		return m, fmt.Errorf("invalid amount %q: more than 4 decimal places", s)
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return m, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	units := int64(0)
+	if intPart != "" {
+		whole, err := strconv.ParseInt(intPart, 10, 64)
+		if err != nil || whole > math.MaxInt64/moneyScale {
+			return m, fmt.Errorf("invalid amount %q", s)
+		}
+		units = whole * moneyScale
+	}
+	if fracPart != "" {
+		frac, _ := strconv.ParseInt(fracPart+strings.Repeat("0", 4-len(fracPart)), 10, 64)
+		if units > math.MaxInt64-frac {
+			return m, fmt.Errorf("invalid amount %q: too large", s)
+		}
+		units += frac
+	}
+
+	if negative {
+		units = -units
+	}
+	m.Units = units
+	return m, nil
+}
+
+// Add returns the sum of two amounts. Callers only add amounts in the same
+// currency; the result takes the currency of whichever side has one.
+func (m Money) Add(o Money) Money {
+	if m.Currency == "" {
+		m.Currency = o.Currency
+	}
+	m.Units += o.Units
+	return m
+}
+
+// Neg returns the amount with its sign flipped
+func (m Money) Neg() Money {
+	m.Units = -m.Units
+	return m
+}
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m.Units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Cmp compares the amounts of m and o, ignoring currency
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.Units < o.Units:
+		return -1
+	case m.Units > o.Units:
+		return 1
+	}
+	return 0
+}
+
+// Convert multiplies the amount by an exchange rate into another currency,
+// rounding half away from zero to cents
+func (m Money) Convert(rate *big.Rat, currency string) Money {
+	cents := new(big.Rat).Mul(big.NewRat(m.Units, moneyScale/100), rate)
+	q, r := new(big.Int).QuoRem(cents.Num(), cents.Denom(), new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(cents.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(cents.Sign())))
+	}
+	return Money{Units: q.Int64() * (moneyScale / 100), Currency: currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Units == 0
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of the amount
+func (m Money) Sign() int {
+	return m.Cmp(Money{})
+}
+
+// Float64 returns an approximate value, for charts and ratios only
+func (m Money) Float64() float64 {
+	return float64(m.Units) / moneyScale
+}
+
+// String renders a plain decimal with at least two decimal places
+func (m Money) String() string {
+	units := m.Units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	whole := units / moneyScale
+	frac := fmt.Sprintf("%04d", units%moneyScale)
+	frac = strings.TrimRight(frac, "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	return fmt.Sprintf("%s%d.%s", sign, whole, frac)
+}
+
+// Format renders the amount rounded to two decimals with comma separators
+func (m Money) Format() string {
+	// Round half away from zero to cents
+	units := m.Units
+	negative := units < 0
+	if negative {
+		units = -units
+	}
+	cents := (units + moneyScale/200) / (moneyScale / 100)
+
+	intPart := strconv.FormatInt(cents/100, 10)
+	decPart := fmt.Sprintf("%02d", cents%100)
+
+	// Add commas to integer part
+	var result []rune
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			result = append(result, ',')
+		}
+		result = append(result, r)
+	}
+
+	formatted := string(result) + "." + decPart
+	if negative && cents != 0 {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// MarshalJSON encodes the amount as an exact decimal number with its
+// currency, e.g. {"amount":1234.5,"currency":"TWD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}{json.Number(m.String()), m.Currency})
+}
+
+// Display renders the amount with its currency, e.g. NT$1,234.00
+func (m Money) Display() string {
+	return currencyPrefix(m.Currency) + m.Format()
+}
+
+// currencyPrefix returns the prefix shown before amounts in a currency
+func currencyPrefix(currency string) string {
+	switch currency {
+	case "", "TWD":
+		return "NT$"
+	default:
+		return currency + " "
+	}
+}
+
+// currencyLabel returns the short label for a currency column, keeping the
+// NTD label the HSBC export uses for Taiwan dollars
+func currencyLabel(currency string) string {
+	if currency == "" || currency == "TWD" {
+		return "NTD"
+	}
+	return currency
+}
+
+// Totals accumulates amounts per currency
+type Totals map[string]Money
+
+// Add adds an amount to the total for its currency
+func (t Totals) Add(m Money) {
+	t[m.Currency] = t[m.Currency].Add(m)
+}
+
+// Currencies returns the currencies present, TWD first and the rest sorted
+func (t Totals) Currencies() []string {
+	currencies := make([]string, 0, len(t))
+	for c := range t {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		if (currencies[i] == "TWD") != (currencies[j] == "TWD") {
+			return currencies[i] == "TWD"
+		}
+		return currencies[i] < currencies[j]
+	})
+	return currencies
+}
+
+// MarshalJSON encodes the totals as a list of amounts in the order of
+// Currencies
+func (t Totals) MarshalJSON() ([]byte, error) {
+	amounts := make([]Money, 0, len(t))
+	for _, c := range t.Currencies() {
+		m := t[c]
+		m.Currency = c
+		amounts = append(amounts, m)
+	}
+	return json.Marshal(amounts)
+}
+
+// String renders every currency total, e.g. "NT$1,200.00 + EUR 35.10"
+func (t Totals) String() string {
+	if len(t) == 0 {
+		return Money{Currency: "TWD"}.Display()
+	}
+
+	parts := make([]string, 0, len(t))
+	for _, c := range t.Currencies() {
+		parts = append(parts, t[c].Display())
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		amount, rate, want string
+	}{
+		{"5.00", "33.10", "165.50"},
+		{"-5.00", "33.10", "-165.50"},
+		{"165.50", "10/331", "5.00"},
+		{"1.00", "0.005", "0.01"},
+		{"-1.00", "0.005", "-0.01"},
+		{"1.00", "0.0049", "0.00"},
+	}
+	for _, tt := range tests {
+		amount, err := ParseMoney(tt.amount, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rate, _ := new(big.Rat).SetString(tt.rate)
+		got := amount.Convert(rate, "TWD")
+		if got.String() != tt.want || got.Currency != "TWD" {
+			t.Errorf("%s × %s = %s, want %s TWD", tt.amount, tt.rate, got.Display(), tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in    string
+		units int64
+		err   bool
+	}{
+		{"", 0, false},
+		{"12", 120000, false},
+		{"-12", -120000, false},
+		{"+12.5", 125000, false},
+		{" 0.0001 ", 1, false},
+		{".5", 5000, false},
+		{"-1.2345", -12345, false},
+		{"1.23456", 0, true},
+		{"1,234.56", 12345600, false},
+		{"1,234,567", 12345670000, false},
+		{"1,2,3", 0, true},
+		{"12,34", 0, true},
+		{",123", 0, true},
+		{"1234,567", 0, true},
+		{"1,234.5,6", 0, true},
+		{"922337203685477.5807", math.MaxInt64, false},
+		{"922337203685477.9999", 0, true},
+		{"922337203685478", 0, true},
+		{"-", 0, true},
+		{".", 0, true},
+		{"1e3", 0, true},
+		{"--1", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in, "TWD")
+		if (err != nil) != tt.err {
+			t.Errorf("ParseMoney(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && (got.Units != tt.units || got.Currency != "TWD") {
+			t.Errorf("ParseMoney(%q) = %+v, want %d units", tt.in, got, tt.units)
+		}
+	}
+}
+
+func TestMoneyRendering(t *testing.T) {
+	tests := []struct {
+		units                int64
+		currency             string
+		str, format, display string
+	}{
+		{0, "TWD", "0.00", "0.00", "NT$0.00"},
+		{12345600, "TWD", "1234.56", "1,234.56", "NT$1,234.56"},
+		{-12345, "USD", "-1.2345", "-1.23", "USD -1.23"},
+		{1234567895, "", "123456.7895", "123,456.79", "NT$123,456.79"},
+		{-49, "EUR", "-0.0049", "0.00", "EUR 0.00"},
+		{-50, "EUR", "-0.005", "-0.01", "EUR -0.01"},
+		{10000000, "JPY", "1000.00", "1,000.00", "JPY 1,000.00"},
+	}
+	for _, tt := range tests {
+		m := Money{Units: tt.units, Currency: tt.currency}
+		if got := m.String(); got != tt.str {
+			t.Errorf("String(%d) = %q, want %q", tt.units, got, tt.str)
+		}
+		if got := m.Format(); got != tt.format {
+			t.Errorf("Format(%d) = %q, want %q", tt.units, got, tt.format)
+		}
+		if got := m.Display(); got != tt.display {
+			t.Errorf("Display(%d %s) = %q, want %q", tt.units, tt.currency, got, tt.display)
+		}
+	}
+}
+
+func TestTotals(t *testing.T) {
+	totals := Totals{}
+	if got := totals.String(); got != "NT$0.00" {
+		t.Errorf("empty totals = %q", got)
+	}
+
+	for _, m := range []Money{
+		{Units: 351000, Currency: "EUR"},
+		{Units: 12000000, Currency: "TWD"},
+		{Units: 100000, Currency: "AUD"},
+		{Units: -2000000, Currency: "TWD"},
+	} {
+		totals.Add(m)
+	}
+	if got := strings.Join(totals.Currencies(), " "); got != "TWD AUD EUR" {
+		t.Errorf("Currencies() = %s", got)
+	}
+	if got := totals.String(); got != "NT$1,000.00 + AUD 10.00 + EUR 35.10" {
+		t.Errorf("String() = %q", got)
+	}
+	data, err := json.Marshal(totals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"amount":1000.00,"currency":"TWD"},{"amount":10.00,"currency":"AUD"},{"amount":35.10,"currency":"EUR"}]`
+	if string(data) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterParser(mt940Parser{}, priorityFormat)
+}
+
+// mt940Parser reads SWIFT MT940 customer statement messages
+type mt940Parser struct{}
+
+func (mt940Parser) Name() string {
+	return "MT940"
+}
+
+// Detect looks for the reference, account and opening balance tags
+func (mt940Parser) Detect(r io.Reader) bool {
+	head, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(head, []byte(":20:")) &&
+		bytes.Contains(head, []byte(":25:")) &&
+		(bytes.Contains(head, []byte(":60F:")) || bytes.Contains(head, []byte(":60M:")))
+}
+
+// mt940Field is a tag with its (possibly multi-line) content
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// mt940Balance matches :60F:, :60M:, :62F: and :62M: balances, e.g. C250331EUR3120,55
+var mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})([\d,]+)`)
+
+// mt940Line matches a :61: statement line: value date, optional entry date,
+// debit/credit mark, optional funds code and amount
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([\d,]+)`)
+
+// mt940Subfield splits structured :86: content such as ?20PURPOSE?32NAME
+var mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+
+// Parse maps each account onto one statement per month. Credits are
+// positive and debits negative; the entry (booking) date becomes
+// PostingDate and the value date TxnDate.
+func (mt940Parser) Parse(r io.Reader) ([]Statement, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	var stmt *Statement
+	account := ""
+
+	flush := func() {
+		if stmt != nil {
+			statements = append(statements, *stmt)
+			stmt = nil
+		}
+	}
+
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			flush()
+			stmt = &Statement{CardType: "MT940"}
+
+		case "25":
+			if stmt == nil {
+				return nil, errors.New(":25: before :20:")
+			}
+			account = strings.TrimSpace(f.value)
+			stmt.PaymentKey = "mt940:" + account
+
+		case "60F", "60M":
+			if stmt == nil {
+				return nil, errors.New(":60: before :20:")
+			}
+			m := mt940Balance.FindStringSubmatch(f.value)
+			if m == nil {
+				return nil, fmt.Errorf("invalid opening balance %q", f.value)
+			}
+			stmt.Currency = m[3]
+			stmt.PreBal = signedAmount(strings.ReplaceAll(m[4], ",", "."), m[1] == "D")
+
+		case "62F", "62M":
+			if stmt == nil {
+				return nil, errors.New(":62: before :20:")
+			}
+			m := mt940Balance.FindStringSubmatch(f.value)
+			if m == nil {
+				return nil, fmt.Errorf("invalid closing balance %q", f.value)
+			}
+			date := mt940Date(m[2])
+			stmt.CurTotAmt = signedAmount(strings.ReplaceAll(m[4], ",", "."), m[1] == "D")
+			stmt.StmtDate = date
+			stmt.StmtYr = date[:4]
+			stmt.StmtMo = date[5:7]
+
+		case "61":
+			if stmt == nil {
+				return nil, errors.New(":61: before :20:")
+			}
+			m := mt940Line.FindStringSubmatch(f.value)
+			if m == nil {
+				return nil, fmt.Errorf("invalid statement line %q", f.value)
+			}
+
+			// RC reverses a credit (money out), RD reverses a debit (money in)
+			debit := m[3] == "D" || m[3] == "RC"
+			amount := signedAmount(strings.ReplaceAll(m[5], ",", "."), debit)
+
+			valueDate := mt940Date(m[1])
+			bookingDate := valueDate
+			if m[2] != "" {
+				bookingDate = mt940EntryDate(valueDate, m[2])
+			}
+
+			stmt.Transactions = append(stmt.Transactions, Transaction{
+				Amount:      amount,
+				NtdAmount:   amount,
+				TxnDate:     valueDate,
+				PostingDate: bookingDate,
+				CardNo:      lastDigits(account, 4),
+			})
+
+		case "86":
+			// Information to account owner belongs to the preceding :61:
+			if stmt == nil || len(stmt.Transactions) == 0 {
+				continue
+			}
+			tx := &stmt.Transactions[len(stmt.Transactions)-1]
+			tx.Description = mt940Description(f.value)
+		}
+	}
+	flush()
+
+	if len(statements) == 0 {
+		return nil, errors.New("no statements found")
+	}
+	for _, s := range statements {
+		if s.StmtDate == "" {
+			return nil, fmt.Errorf("statement for account %q has no closing balance", s.PaymentKey)
+		}
+	}
+
+	return mergeMonthlyStatements(statements), nil
+}
+
+// readMT940Fields splits the message into tagged fields, joining
+// continuation lines and skipping SWIFT envelope blocks
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		line = strings.TrimPrefix(line, "{4:")
+
+		switch {
+		case line == "" || line == "-" || line == "-}" || strings.HasPrefix(line, "{"):
+			continue
+		case strings.HasPrefix(line, ":"):
+			end := strings.Index(line[1:], ":")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field %q", line)
+			}
+			fields = append(fields, mt940Field{tag: line[1 : end+1], value: line[end+2:]})
+		case len(fields) > 0:
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+
+	return fields, scanner.Err()
+}
+
+// mt940Date converts YYMMDD to YYYY-MM-DD
+func mt940Date(s string) string {
+	return "20" + s[:2] + "-" + s[2:4] + "-" + s[4:6]
+}
+
+// mt940EntryDate completes an MMDD entry date with the value date's year,
+// rolling over when the entry is booked across a year boundary
+func mt940EntryDate(valueDate, mmdd string) string {
+	year, _ := strconv.Atoi(valueDate[:4])
+	valueMonth, _ := strconv.Atoi(valueDate[5:7])
+	entryMonth, _ := strconv.Atoi(mmdd[:2])
+
+	switch {
+	case valueMonth == 12 && entryMonth == 1:
+		year++
+	case valueMonth == 1 && entryMonth == 12:
+		year--
+	}
+	return fmt.Sprintf("%04d-%s-%s", year, mmdd[:2], mmdd[2:])
+}
+
+// mt940Description extracts the counterparty name or purpose from :86:.
+// Structured content (?20-?29 purpose, ?32-?33 name) is used when present.
+func mt940Description(value string) string {
+	value = strings.ReplaceAll(value, "\n", "")
+	if !strings.Contains(value, "?") {
+		return strings.TrimSpace(value)
+	}
+
+	var purpose, name strings.Builder
+	indexes := mt940Subfield.FindAllStringSubmatchIndex(value, -1)
+	for i, idx := range indexes {
+		end := len(value)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		code, _ := strconv.Atoi(value[idx[2]:idx[3]])
+		content := value[idx[1]:end]
+
+		switch {
+		case code >= 20 && code <= 29:
+			purpose.WriteString(content)
+		case code == 32 || code == 33:
+			name.WriteString(content)
+		}
+	}
+
+	if s := strings.TrimSpace(name.String()); s != "" {
+		return s
+	}
+	return strings.TrimSpace(purpose.String())
+}
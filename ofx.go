@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math/big"
+	"strings"
+)
+
+func init() {
+	RegisterParser(ofxParser{}, priorityFormat)
+}
+
+// ofxParser reads OFX 1.x (SGML) and 2.x (XML) bank and credit card
+// statements, including Quicken's QFX flavour
+type ofxParser struct{}
+
+func (ofxParser) Name() string {
+	return "OFX"
+}
+
+// Detect looks for the OFX header or root element near the start of the input
+func (ofxParser) Detect(r io.Reader) bool {
+	head, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return false
+	}
+	head = bytes.ToUpper(head)
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+func (ofxParser) Parse(r io.Reader) ([]Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseOFXTree(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	for _, stmtrs := range root.findAll("STMTRS") {
+		stmt, err := ofxStatement(stmtrs, "BANKACCTFROM", false)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	for _, stmtrs := range root.findAll("CCSTMTRS") {
+		stmt, err := ofxStatement(stmtrs, "CCACCTFROM", true)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+
+	if len(statements) == 0 {
+		return nil, errors.New("no STMTRS or CCSTMTRS aggregate found")
+	}
+	return statements, nil
+}
+
+// ofxStatement maps a statement response aggregate onto a Statement.
+// Credit card amounts are negated so purchases are positive like the HSBC
+// export; bank account amounts keep their sign.
+func ofxStatement(stmtrs *ofxNode, accountTag string, creditCard bool) (Statement, error) {
+	currency := strings.ToUpper(stmtrs.value("CURDEF"))
+	accountID := ""
+	if account := stmtrs.find(accountTag); account != nil {
+		accountID = account.value("ACCTID")
+	}
+
+	stmt := Statement{
+		PaymentKey: "ofx:" + accountID,
+		CardType:   "OFX",
+		Currency:   currency,
+	}
+
+	tranList := stmtrs.find("BANKTRANLIST")
+	endDate := ""
+	if tranList != nil {
+		endDate = ofxDate(tranList.value("DTEND"))
+	}
+	if ledger := stmtrs.find("LEDGERBAL"); ledger != nil {
+		balance, err := ofxAmount(ledger.value("BALAMT"), currency)
+		if err != nil {
+			return stmt, fmt.Errorf("LEDGERBAL: %w", err)
+		}
+		if creditCard {
+			balance = balance.Neg()
+		}
+		stmt.CurTotAmt = balance.String()
+		if endDate == "" {
+			endDate = ofxDate(ledger.value("DTASOF"))
+		}
+	}
+	if len(endDate) < 7 {
+		return stmt, fmt.Errorf("statement for account %q has no end date", accountID)
+	}
+	stmt.StmtDate = endDate
+	stmt.StmtYr = endDate[:4]
+	stmt.StmtMo = endDate[5:7]
+
+	if tranList == nil {
+		return stmt, nil
+	}
+
+	for _, trn := range tranList.findAll("STMTTRN") {
+		amount, err := ofxAmount(trn.value("TRNAMT"), currency)
+		if err != nil {
+			return stmt, fmt.Errorf("STMTTRN %s: %w", trn.value("FITID"), err)
+		}
+		if creditCard {
+			amount = amount.Neg()
+		}
+
+		tx := Transaction{
+			Description: trn.value("NAME"),
+			TxnDate:     ofxDate(trn.value("DTUSER")),
+			PostingDate: ofxDate(trn.value("DTPOSTED")),
+			CardNo:      lastDigits(accountID, 4),
+			Amount:      amount.String(),
+			NtdAmount:   amount.String(),
+		}
+		if tx.Description == "" {
+			tx.Description = trn.value("MEMO")
+		}
+		if tx.TxnDate == "" {
+			tx.TxnDate = tx.PostingDate
+		}
+
+		// ORIGCURRENCY: TRNAMT is in the account currency, converted from CURSYM.
+		// CURRENCY: TRNAMT is in CURSYM and converts to the account currency.
+		// Without a usable rate the converted amount is left blank.
+		if orig := trn.find("ORIGCURRENCY"); orig != nil {
+			tx.AmtCy = strings.ToUpper(orig.value("CURSYM"))
+			rate, err := ofxRate(orig.value("CURRATE"))
+			if err != nil {
+				tx.Amount = ""
+				stmt.Issues = append(stmt.Issues, fmt.Sprintf("STMTTRN %s: ORIGCURRENCY: %v", trn.value("FITID"), err))
+			} else {
+				tx.Amount = amount.Convert(new(big.Rat).Inv(rate), tx.AmtCy).String()
+			}
+		} else if cur := trn.find("CURRENCY"); cur != nil {
+			tx.AmtCy = strings.ToUpper(cur.value("CURSYM"))
+			rate, err := ofxRate(cur.value("CURRATE"))
+			if err != nil {
+				tx.NtdAmount = ""
+				stmt.Issues = append(stmt.Issues, fmt.Sprintf("STMTTRN %s: CURRENCY: %v", trn.value("FITID"), err))
+			} else {
+				tx.NtdAmount = amount.Convert(rate, currency).String()
+			}
+		}
+		if tx.AmtCy == currency {
+			tx.AmtCy = ""
+		}
+		tx.IsForeignTxn = tx.AmtCy != ""
+
+		stmt.Transactions = append(stmt.Transactions, tx)
+	}
+
+	return stmt, nil
+}
+
+// ofxDate converts an OFX datetime (YYYYMMDD[HHMMSS[.XXX]][[gmt offset]]) to YYYY-MM-DD
+func ofxDate(s string) string {
+	if len(s) < 8 {
+		return ""
+	}
+	return s[:4] + "-" + s[4:6] + "-" + s[6:8]
+}
+
+// ofxAmount parses an OFX amount, which may use a comma as decimal separator
+func ofxAmount(s, currency string) (Money, error) {
+	return ParseMoney(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), currency)
+}
+
+// ofxRate parses the CURRATE of a foreign currency aggregate, which must be
+// a positive decimal
+func ofxRate(s string) (*big.Rat, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return nil, errors.New("missing CURRATE")
+	}
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid CURRATE %q", s)
+	}
+	return rate, nil
+}
+
+// lastDigits returns the last n characters of an account or card number
+func lastDigits(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+// ofxNode is an element of an OFX document. Leaf elements carry text;
+// aggregates carry children.
+type ofxNode struct {
+	name     string
+	text     string
+	children []*ofxNode
+}
+
+// find returns the first descendant with the given tag
+func (n *ofxNode) find(name string) *ofxNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every descendant with the given tag
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var found []*ofxNode
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+		} else {
+			found = append(found, child.findAll(name)...)
+		}
+	}
+	return found
+}
+
+// value returns the text of the first descendant with the given tag
+func (n *ofxNode) value(name string) string {
+	if child := n.find(name); child != nil {
+		return child.text
+	}
+	return ""
+}
+
+// parseOFXTree builds an element tree from either OFX flavour. SGML leaf
+// elements have no closing tag, so an element that received text is closed
+// as soon as the next tag starts; closing tags pop up to the matching open
+// element.
+func parseOFXTree(doc string) (*ofxNode, error) {
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("missing <OFX> root element")
+	}
+	doc = doc[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	top := func() *ofxNode { return stack[len(stack)-1] }
+
+	for len(doc) > 0 {
+		lt := strings.IndexByte(doc, '<')
+		if lt < 0 {
+			break
+		}
+		if text := strings.TrimSpace(doc[:lt]); text != "" && len(stack) > 1 {
+			top().text = html.UnescapeString(text)
+		}
+		doc = doc[lt:]
+
+		gt := strings.IndexByte(doc, '>')
+		if gt < 0 {
+			return nil, errors.New("unterminated tag")
+		}
+		tag := strings.TrimSpace(doc[1:gt])
+		doc = doc[gt+1:]
+
+		// Processing instructions, comments and declarations carry no data
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		// Close a pending leaf before opening or closing anything else
+		if len(stack) > 1 && top().text != "" {
+			stack = stack[:len(stack)-1]
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimPrefix(tag, "/"))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		fields := strings.Fields(strings.TrimSuffix(tag, "/"))
+		if len(fields) == 0 {
+			continue
+		}
+		node := &ofxNode{name: strings.ToUpper(fields[0])}
+		top().children = append(top().children, node)
+		if !selfClosing {
+			stack = append(stack, node)
+		}
+	}
+
+	if len(root.children) == 0 {
+		return nil, errors.New("empty OFX document")
+	}
+	return root, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// parseOFXSample parses an OFX sample from testdata/ofx, after replacing old
+// with new in its text
+func parseOFXSample(t *testing.T, name, old, new string) []Statement {
+	t.Helper()
+	data, err := os.ReadFile("testdata/ofx/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err := ofxParser{}.Parse(strings.NewReader(strings.Replace(string(data), old, new, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+func TestOFXParse(t *testing.T) {
+	type row struct{ desc, date, amount, billed, currency string }
+	tests := []struct {
+		file     string
+		key      string
+		period   string
+		currency string
+		total    string
+		rows     []row
+	}{
+		{
+			// OFX 1.x SGML bank statement: amounts keep their sign
+			file: "checking-v1.ofx", key: "ofx:IT60X0542811101000000123456",
+			period: "2025/03", currency: "EUR", total: "3120.55",
+			rows: []row{
+				{"ESSELUNGA MILANO", "2025-03-02", "-45.20", "-45.20", ""},
+				{"STIPENDIO MARZO", "2025-03-27", "2500.00", "2500.00", ""},
+				{"AMAZON MKTPLACE & CO", "2025-03-28", "-21.73", "-19.99", "USD"},
+			},
+		},
+		{
+			// OFX 2.x XML credit card statement from Quicken: purchases
+			// become positive and payments negative
+			file: "creditcard-v2.qfx", key: "ofx:4000123412349876",
+			period: "2025/04", currency: "TWD", total: "4535.50",
+			rows: []row{
+				{"STARBUCKS TAIPEI 101", "2025-03-17", "180.00", "180.00", ""},
+				{"UBER *TRIP", "2025-03-31", "5.00", "165.50", "USD"},
+				{"PAYMENT THANK YOU", "2025-04-05", "-12000.00", "-12000.00", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/ofx/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p := DetectParser(data); p == nil || p.Name() != "OFX" {
+				t.Fatalf("detected parser %v, want OFX", p)
+			}
+
+			statements := parseOFXSample(t, tt.file, "", "")
+			if len(statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(statements))
+			}
+			stmt := statements[0]
+			if stmt.PaymentKey != tt.key || stmt.StmtYr+"/"+stmt.StmtMo != tt.period || stmt.Currency != tt.currency {
+				t.Errorf("statement %s %s/%s in %s, want %s %s in %s", stmt.PaymentKey, stmt.StmtYr, stmt.StmtMo, stmt.Currency, tt.key, tt.period, tt.currency)
+			}
+			if stmt.CurTotAmt != tt.total {
+				t.Errorf("total = %s, want the LEDGERBAL %s", stmt.CurTotAmt, tt.total)
+			}
+			if len(stmt.Issues) > 0 {
+				t.Errorf("unexpected issues %q", stmt.Issues)
+			}
+			if len(stmt.Transactions) != len(tt.rows) {
+				t.Fatalf("got %d transactions, want %d", len(stmt.Transactions), len(tt.rows))
+			}
+			for i, want := range tt.rows {
+				tx := stmt.Transactions[i]
+				got := row{tx.Description, tx.TxnDate, tx.Amount, tx.NtdAmount, tx.AmtCy}
+				if got != want {
+					t.Errorf("transaction %d = %+v, want %+v", i+1, got, want)
+				}
+				if tx.IsForeignTxn != (want.currency != "") {
+					t.Errorf("transaction %d foreign = %v", i+1, tx.IsForeignTxn)
+				}
+			}
+		})
+	}
+}
+
+func TestOFXForeignCurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      string
+		ntdAmount string
+		issue     string
+	}{
+		{"with rate", "<CURRATE>33.10</CURRATE>", "165.50", ""},
+		{"missing rate", "", "", "missing CURRATE"},
+		{"zero rate", "<CURRATE>0</CURRATE>", "", `invalid CURRATE "0"`},
+		{"malformed rate", "<CURRATE>n/a</CURRATE>", "", `invalid CURRATE "n/a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements := parseOFXSample(t, "creditcard-v2.qfx", "<CURRATE>33.10</CURRATE>", tt.rate)
+			stmt := statements[0]
+			uber := stmt.Transactions[1]
+			if uber.Amount != "5.00" || uber.AmtCy != "USD" {
+				t.Errorf("amount = %s %s, want 5.00 USD", uber.Amount, uber.AmtCy)
+			}
+			if uber.NtdAmount != tt.ntdAmount {
+				t.Errorf("ntdAmount = %q, want %q", uber.NtdAmount, tt.ntdAmount)
+			}
+			switch {
+			case tt.issue == "" && len(stmt.Issues) > 0:
+				t.Errorf("unexpected issues %q", stmt.Issues)
+			case tt.issue != "" && (len(stmt.Issues) != 1 || !strings.Contains(stmt.Issues[0], tt.issue)):
+				t.Errorf("issues = %q, want one containing %q", stmt.Issues, tt.issue)
+			}
+		})
+	}
+}
+
+func TestOFXRateIssueIsLoadIssue(t *testing.T) {
+	statements := parseOFXSample(t, "creditcard-v2.qfx", "<CURRATE>33.10</CURRATE>", "")
+	issues := normalizeStatements(statements)
+	found := false
+	for _, issue := range issues {
+		found = found || strings.Contains(issue, "STMTTRN CC002: CURRENCY: missing CURRATE")
+	}
+	if !found {
+		t.Errorf("load issues %q miss the rate", issues)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Override is a manual correction or annotation attached to a single
+// transaction
+type Override struct {
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Note        string   `json:"note,omitempty"`
+	Description string   `json:"description,omitempty"` // Copy of the description so the file can be read by hand
+}
+
+// isEmpty reports whether the override no longer changes anything
+func (o Override) isEmpty() bool {
+	return o.Category == "" && len(o.Tags) == 0 && o.Note == ""
+}
+
+// ParseTags splits user input such as "#business, #trip-japan-2025" into
+// lowercase tags without the leading #, dropping duplicates
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		tag := strings.ToLower(strings.TrimLeft(field, "#"))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// FormatTags renders tags the way they are typed, e.g. "#business #reimbursable"
+func FormatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "#" + tag
+	}
+	return strings.Join(parts, " ")
+}
+
+// HasTag reports whether a transaction carries a tag
+func (tx Transaction) HasTag(tag string) bool {
+	for _, t := range tx.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// OverrideStore holds overrides keyed by transaction fingerprint, along with
+// the sidecar file they are saved to
+type OverrideStore struct {
+	Path      string
+	Overrides map[string]Override
+}
+
+// overridesFile is the on-disk layout of the sidecar file
+type overridesFile struct {
+	Overrides map[string]Override `json:"overrides"`
+}
+
+// Fingerprint identifies a transaction across runs by its card, date,
+// billed amount and description. Identical transactions on the same day
+// share a fingerprint and therefore an override.
+func Fingerprint(tx Transaction) string {
+	date := tx.TxnDate
+	if !tx.TransactionDate.IsZero() {
+		date = tx.TransactionDate.Format("2006-01-02")
+	}
+	desc := strings.TrimSpace(ToCDB(tx.Description))
+
+	key := strings.Join([]string{
+		tx.CardNo,
+		date,
+		tx.BilledAmount.Currency + " " + tx.BilledAmount.String(),
+		desc,
+	}, "|")
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadOverrides reads the explicit sidecar file, or overrides.json from the
+// configuration directory. A missing file gives an empty store that is
+// created on the first save.
+func LoadOverrides(explicit string) (*OverrideStore, error) {
+	store := &OverrideStore{Path: explicit, Overrides: make(map[string]Override)}
+	if store.Path == "" {
+		store.Path = configPath("overrides.json")
+		if store.Path == "" {
+			return store, nil
+		}
+	}
+
+	data, err := os.ReadFile(store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file overridesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", store.Path, err)
+	}
+	for fp, o := range file.Overrides {
+		o.Category = NormalizeCategory(o.Category)
+		o.Tags = ParseTags(strings.Join(o.Tags, " "))
+		store.Overrides[fp] = o
+	}
+
+	return store, nil
+}
+
+// Get returns the override for a transaction
+func (s *OverrideStore) Get(tx Transaction) (Override, bool) {
+	o, ok := s.Overrides[Fingerprint(tx)]
+	return o, ok
+}
+
+// SetCategory overrides the category of a transaction; an empty category
+// goes back to automatic categorization
+func (s *OverrideStore) SetCategory(tx Transaction, category string) {
+	s.update(tx, func(o *Override) { o.Category = category })
+}
+
+// SetTags replaces the tags of a transaction
+func (s *OverrideStore) SetTags(tx Transaction, tags []string) {
+	s.update(tx, func(o *Override) { o.Tags = tags })
+}
+
+// SetNote replaces the note of a transaction
+func (s *OverrideStore) SetNote(tx Transaction, note string) {
+	s.update(tx, func(o *Override) { o.Note = strings.TrimSpace(note) })
+}
+
+// update changes the override of a transaction, removing it once empty
+func (s *OverrideStore) update(tx Transaction, change func(o *Override)) {
+	fp := Fingerprint(tx)
+	o := s.Overrides[fp]
+	change(&o)
+	o.Description = tx.Description
+	if o.isEmpty() {
+		delete(s.Overrides, fp)
+		return
+	}
+	s.Overrides[fp] = o
+}
+
+// Apply replaces the category of every overridden transaction and attaches
+// tags and notes. It returns how many transactions were changed and runs
+// after CategorizeTransactions.
+func (s *OverrideStore) Apply(statements []Statement) int {
+	if len(s.Overrides) == 0 {
+		return 0
+	}
+
+	applied := 0
+	for i := range statements {
+		for j := range statements[i].Transactions {
+			tx := &statements[i].Transactions[j]
+			o, ok := s.Get(*tx)
+			if !ok {
+				continue
+			}
+			if o.Category != "" {
+				tx.Category = o.Category
+				tx.Confidence = 0
+			}
+			tx.Tags = o.Tags
+			tx.Note = o.Note
+			applied++
+		}
+	}
+	return applied
+}
+
+// Categories returns every category assigned by an override
+func (s *OverrideStore) Categories() []string {
+	var categories []string
+	for _, o := range s.Overrides {
+		if o.Category != "" {
+			categories = append(categories, o.Category)
+		}
+	}
+	return categories
+}
+
+// Save writes the store to its sidecar file, replacing it atomically
+func (s *OverrideStore) Save() error {
+	if s.Path == "" {
+		return errors.New("no overrides file: set -overrides or a configuration directory")
+	}
+
+	data, err := json.MarshalIndent(overridesFile{Overrides: s.Overrides}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// TagTotal is the billed total of the transactions carrying a tag
+type TagTotal struct {
+	Tag    string `json:"tag"`
+	Count  int    `json:"count"`
+	Totals Totals `json:"totals"`
+}
+
+// TagTotals totals billed amounts per tag, sorted by tag. A transaction
+// with several tags counts towards each of them.
+func TagTotals(txs []Transaction) []TagTotal {
+	byTag := make(map[string]*TagTotal)
+	for _, tx := range txs {
+		for _, tag := range tx.Tags {
+			total, ok := byTag[tag]
+			if !ok {
+				total = &TagTotal{Tag: tag, Totals: Totals{}}
+				byTag[tag] = total
+			}
+			total.Count++
+			total.Totals.Add(tx.BilledAmount)
+		}
+	}
+
+	totals := make([]TagTotal, 0, len(byTag))
+	for _, total := range byTag {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Tag < totals[j].Tag })
+	return totals
+}
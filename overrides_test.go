@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFingerprintIsStable(t *testing.T) {
+	tx := journalTx("STARBUCKS MILANO", 150, "2025-03-02", "2025-03-03")
+	// sha1 of "1234|2025-03-02|TWD 150.00|STARBUCKS MILANO"; overrides saved by
+	// earlier runs are keyed on it
+	const want = "26c55d9499f0e6aa32295d04138ba12ee98d872b"
+	if got := Fingerprint(tx); got != want {
+		t.Errorf("Fingerprint() = %s, want %s", got, want)
+	}
+
+	// Categorizing, annotating or reformatting must not change it
+	same := tx
+	same.Category, same.Tags, same.Note = CategoryTravel, []string{"trip"}, "with Anna"
+	same.Description = "ＳＴＡＲＢＵＣＫＳ　ＭＩＬＡＮＯ "
+	same.PostedDate = day("2025-03-04")
+	if got := Fingerprint(same); got != want {
+		t.Errorf("Fingerprint() of the annotated copy = %s, want %s", got, want)
+	}
+
+	for _, change := range []func(*Transaction){
+		func(tx *Transaction) { tx.CardNo = "9999" },
+		func(tx *Transaction) { tx.TransactionDate = day("2025-03-03") },
+		func(tx *Transaction) { tx.BilledAmount = twd(151) },
+		func(tx *Transaction) { tx.Description = "STARBUCKS ROMA" },
+	} {
+		other := tx
+		change(&other)
+		if Fingerprint(other) == want {
+			t.Errorf("Fingerprint() of %+v did not change", other)
+		}
+	}
+}
+
+func TestOverridesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "overrides.json")
+	store, err := LoadOverrides(path)
+	if err != nil || len(store.Overrides) != 0 {
+		t.Fatalf("LoadOverrides() of a missing file = %+v, %v", store, err)
+	}
+
+	coffee := journalTx("STARBUCKS MILANO", 150, "2025-03-02", "2025-03-03")
+	bus := journalTx("ATM BUS", 2, "2025-03-02", "2025-03-02")
+	store.SetCategory(coffee, "Food > Coffee")
+	store.SetTags(coffee, ParseTags("#Trip, #trip #work"))
+	store.SetNote(coffee, "  with Anna ")
+	store.SetNote(bus, "cleared")
+	store.SetNote(bus, "")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Overrides, store.Overrides) || len(loaded.Overrides) != 1 {
+		t.Fatalf("reloaded overrides = %+v, want %+v", loaded.Overrides, store.Overrides)
+	}
+
+	// Two identical purchases on one day share a fingerprint, so both get
+	// the override whichever comes first
+	statements := []Statement{{Transactions: []Transaction{coffee, bus, coffee}}}
+	if n := loaded.Apply(statements); n != 2 {
+		t.Errorf("Apply() changed %d transactions, want 2", n)
+	}
+	txs := statements[0].Transactions
+	for _, i := range []int{0, 2} {
+		tx := txs[i]
+		if tx.Category != "Food > Coffee" || !reflect.DeepEqual(tx.Tags, []string{"trip", "work"}) || tx.Note != "with Anna" {
+			t.Errorf("transaction %d = %s %v %q", i, tx.Category, tx.Tags, tx.Note)
+		}
+	}
+	if txs[1].Category != CategoryFood || txs[1].Note != "" {
+		t.Errorf("transaction without override changed: %+v", txs[1])
+	}
+}
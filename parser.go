@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// StatementParser reads statements exported in one bank or file format
+type StatementParser interface {
+	// Name identifies the format in messages
+	Name() string
+	// Detect reports whether the input looks like this format
+	Detect(r io.Reader) bool
+	// Parse reads every statement from the input
+	Parse(r io.Reader) ([]Statement, error)
+}
+
+// ErrUnknownFormat is returned when no registered parser recognizes an input
+var ErrUnknownFormat = errors.New("unrecognized statement format")
+
+// priorityFormat is the detection priority of formats that identify
+// themselves with a header or marker. CSV profiles only match column names
+// and rank below them, by the number of columns they map.
+const priorityFormat = 100
+
+// registeredParser is a format with its detection priority
+type registeredParser struct {
+	StatementParser
+	priority int
+}
+
+// parsers holds registered formats in detection order
+var parsers []registeredParser
+
+// RegisterParser adds a format to the registry, replacing any registered
+// under the same name. Parsers with a higher priority are tried first, and
+// those with equal priority in the order they were registered.
+func RegisterParser(p StatementParser, priority int) {
+	for i, r := range parsers {
+		if r.Name() == p.Name() {
+			parsers = append(parsers[:i], parsers[i+1:]...)
+			break
+		}
+	}
+	i := sort.Search(len(parsers), func(i int) bool { return parsers[i].priority < priority })
+	parsers = append(parsers[:i], append([]registeredParser{{p, priority}}, parsers[i:]...)...)
+}
+
+// DetectParser returns the registered parser of highest priority that
+// recognizes data
+func DetectParser(data []byte) StatementParser {
+	for _, p := range parsers {
+		if p.Detect(bytes.NewReader(data)) {
+			return p.StatementParser
+		}
+	}
+	return nil
+}
+
+// ParseStatements sniffs the format of data and parses it
+func ParseStatements(data []byte) ([]Statement, error) {
+	p := DetectParser(data)
+	if p == nil {
+		return nil, ErrUnknownFormat
+	}
+
+	statements, err := p.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	return statements, nil
+}
+
+// canParse reports whether any registered parser recognizes the file
+func canParse(filename string) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	return DetectParser(data) != nil
+}
+
+// mergeMonthlyStatements folds statements of the same account and month into
+// one, for formats that deliver daily statements. The merged statement keeps
+// the opening balance of the earliest and the closing balance of the latest.
+func mergeMonthlyStatements(statements []Statement) []Statement {
+	var merged []Statement
+	index := make(map[string]int)
+
+	for _, stmt := range statements {
+		key := statementKey(stmt)
+		pos, exists := index[key]
+		if !exists {
+			index[key] = len(merged)
+			merged = append(merged, stmt)
+			continue
+		}
+
+		m := &merged[pos]
+		m.Transactions = append(m.Transactions, stmt.Transactions...)
+		if stmt.StmtDate >= m.StmtDate {
+			m.StmtDate = stmt.StmtDate
+			m.CurTotAmt = stmt.CurTotAmt
+		} else {
+			m.PreBal = stmt.PreBal
+		}
+	}
+
+	return merged
+}
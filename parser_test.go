@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// bankTx is the part of a parsed transaction the bank format tests check
+type bankTx struct {
+	desc, txnDate, postingDate, amount, billed, currency string
+}
+
+func TestBankStatementSamples(t *testing.T) {
+	tests := []struct {
+		file     string
+		parser   string
+		key      string
+		stmtDate string
+		opening  string
+		closing  string
+		rows     []bankTx
+	}{
+		{
+			file: "testdata/camt/account-2025-03.xml", parser: "camt.053",
+			key: "camt:DE89370400440532013000", stmtDate: "2025-03-31",
+			opening: "1200.00", closing: "3610.30",
+			rows: []bankTx{
+				{"ACME GMBH", "2025-03-27", "2025-03-27", "2500.00", "2500.00", ""},
+				{"ESSELUNGA MILANO", "2025-03-03", "2025-03-04", "-45.20", "-45.20", ""},
+				{"CARD PAYMENT AWS EMEA", "2025-03-11", "2025-03-12", "-48.00", "-44.50", "USD"},
+			},
+		},
+		{
+			// Two daily statements folded into March
+			file: "testdata/mt940/account-2025-03.sta", parser: "MT940",
+			key: "mt940:10020030/1234567", stmtDate: "2025-03-31",
+			opening: "1200.00", closing: "3666.80",
+			rows: []bankTx{
+				{"ESSELUNGA SPA", "2025-03-03", "2025-03-04", "-45.20", "-45.20", ""},
+				{"SALARY MARCH ACME GMBH", "2025-03-27", "2025-03-27", "2500.00", "2500.00", ""},
+				{"REFUND COFFEE", "2025-03-30", "2025-03-30", "12.00", "12.00", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.parser, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p := DetectParser(data); p == nil || p.Name() != tt.parser {
+				t.Fatalf("detected parser %v, want %s", p, tt.parser)
+			}
+			statements, err := ParseStatements(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(statements) != 1 {
+				t.Fatalf("got %d statements, want 1 for the month", len(statements))
+			}
+
+			stmt := statements[0]
+			if stmt.PaymentKey != tt.key || stmt.StmtDate != tt.stmtDate || stmt.StmtYr+"/"+stmt.StmtMo != "2025/03" || stmt.Currency != "EUR" {
+				t.Errorf("statement %s dated %s (%s/%s, %s)", stmt.PaymentKey, stmt.StmtDate, stmt.StmtYr, stmt.StmtMo, stmt.Currency)
+			}
+			if stmt.PreBal != tt.opening || stmt.CurTotAmt != tt.closing {
+				t.Errorf("balances %s to %s, want %s to %s", stmt.PreBal, stmt.CurTotAmt, tt.opening, tt.closing)
+			}
+			if len(stmt.Transactions) != len(tt.rows) {
+				t.Fatalf("got %d transactions, want %d", len(stmt.Transactions), len(tt.rows))
+			}
+			for i, want := range tt.rows {
+				tx := stmt.Transactions[i]
+				got := bankTx{tx.Description, tx.TxnDate, tx.PostingDate, tx.Amount, tx.NtdAmount, tx.AmtCy}
+				if got != want {
+					t.Errorf("transaction %d = %+v, want %+v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMT940EntryDate(t *testing.T) {
+	tests := []struct {
+		valueDate, mmdd, want string
+	}{
+		{"2025-03-03", "0304", "2025-03-04"},
+		{"2024-12-31", "0102", "2025-01-02"},
+		{"2025-01-01", "1231", "2024-12-31"},
+	}
+	for _, tt := range tests {
+		if got := mt940EntryDate(tt.valueDate, tt.mmdd); got != tt.want {
+			t.Errorf("mt940EntryDate(%s, %s) = %s, want %s", tt.valueDate, tt.mmdd, got, tt.want)
+		}
+	}
+}
+
+func TestSignedAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		debit bool
+		want  string
+	}{
+		{"45.20", true, "-45.20"},
+		{"2500.00", false, "2500.00"},
+		{"12", false, "12.00"},
+		{"n/a", true, "n/a"},
+	}
+	for _, tt := range tests {
+		if got := signedAmount(tt.value, tt.debit); got != tt.want {
+			t.Errorf("signedAmount(%q, %v) = %s, want %s", tt.value, tt.debit, got, tt.want)
+		}
+	}
+}
+
+func TestMergeMonthlyStatements(t *testing.T) {
+	daily := func(key, date, opening, closing, desc string) Statement {
+		return Statement{
+			PaymentKey: key, StmtYr: date[:4], StmtMo: date[5:7], StmtDate: date,
+			PreBal: opening, CurTotAmt: closing,
+			Transactions: []Transaction{{Description: desc}},
+		}
+	}
+	tests := []struct {
+		name       string
+		statements []Statement
+		want       []Statement
+	}{
+		{
+			name: "in date order",
+			statements: []Statement{
+				daily("a", "2025-03-15", "100.00", "80.00", "one"),
+				daily("a", "2025-03-31", "80.00", "50.00", "two"),
+			},
+			want: []Statement{{PaymentKey: "a", StmtDate: "2025-03-31", PreBal: "100.00", CurTotAmt: "50.00"}},
+		},
+		{
+			name: "latest first",
+			statements: []Statement{
+				daily("a", "2025-03-31", "80.00", "50.00", "two"),
+				daily("a", "2025-03-15", "100.00", "80.00", "one"),
+			},
+			want: []Statement{{PaymentKey: "a", StmtDate: "2025-03-31", PreBal: "100.00", CurTotAmt: "50.00"}},
+		},
+		{
+			name: "other month and account kept apart",
+			statements: []Statement{
+				daily("a", "2025-03-31", "100.00", "80.00", "one"),
+				daily("a", "2025-04-30", "80.00", "50.00", "two"),
+				daily("b", "2025-03-31", "0.00", "10.00", "three"),
+			},
+			want: []Statement{
+				{PaymentKey: "a", StmtDate: "2025-03-31", PreBal: "100.00", CurTotAmt: "80.00"},
+				{PaymentKey: "a", StmtDate: "2025-04-30", PreBal: "80.00", CurTotAmt: "50.00"},
+				{PaymentKey: "b", StmtDate: "2025-03-31", PreBal: "0.00", CurTotAmt: "10.00"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeMonthlyStatements(tt.statements)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d statements, want %d", len(got), len(tt.want))
+			}
+			rows := 0
+			for i, want := range tt.want {
+				g := got[i]
+				if g.PaymentKey != want.PaymentKey || g.StmtDate != want.StmtDate || g.PreBal != want.PreBal || g.CurTotAmt != want.CurTotAmt {
+					t.Errorf("statement %d = %s %s %s..%s, want %s %s %s..%s", i, g.PaymentKey, g.StmtDate, g.PreBal, g.CurTotAmt,
+						want.PaymentKey, want.StmtDate, want.PreBal, want.CurTotAmt)
+				}
+				rows += len(g.Transactions)
+			}
+			if rows != len(tt.statements) {
+				t.Errorf("merged statements hold %d transactions, want %d", rows, len(tt.statements))
+			}
+		})
+	}
+}
+
+func TestDetectParserPrecedence(t *testing.T) {
+	narrow := CSVProfile{Name: "precedence-narrow", DateColumn: "When", AmountColumn: "Sum", DescriptionColumn: "Memo"}
+	wide := narrow
+	wide.Name, wide.CardColumn = "precedence-wide", "Card"
+	RegisterCSVProfiles([]CSVProfile{narrow, wide})
+	registered := len(parsers)
+	RegisterCSVProfiles([]CSVProfile{narrow, wide})
+	if len(parsers) != registered {
+		t.Errorf("registering the profiles again grew the registry from %d to %d parsers", registered, len(parsers))
+	}
+
+	tests := []struct {
+		name, data, want string
+	}{
+		{"more specific profile", "When,Sum,Memo,Card\n2025-03-02,100,COFFEE,1234\n", "CSV (precedence-wide)"},
+		{"only the narrow profile", "When,Sum,Memo\n2025-03-02,100,COFFEE\n", "CSV (precedence-narrow)"},
+		{"format marker over columns", "When,Sum,Memo,Card\n2025-03-02,100,<OFX>,1234\n", "OFX"},
+		{"unknown", "Date,Amount\n", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if p := DetectParser([]byte(tt.data)); p != nil {
+			got = p.Name()
+		}
+		if got != tt.want {
+			t.Errorf("%s: DetectParser() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a parsed filter expression; a transaction matches when every
+// term does:
+//
+//	category:Food          the category or one of its subcategories
+//	desc:UBER              text in the description; loc:, merchant: and note: likewise
+//	currency:EUR           billed or original currency
+//	card:1234              card number or Apple Pay card ending in these digits
+//	tag:business           also #business
+//	amount>500             billed amount; also >=, <, <=, amount:500 and amount:100..500
+//	date:2025-03..2025-05  days, months or years; also date>=2025-03-14 and the like
+//	starbucks              other words are looked for in the description and location
+//	-desc:UBER             a leading - negates a term
+//
+// Bare bounds such as >500, 100..500 and 2025-03-01..2025-03-31 are short
+// for amount and date terms; a negative lower bound needs the field, as in
+// amount:-100..0. Values with spaces are quoted, as in
+// category:"Food > Coffee". The zero Query matches every transaction.
+type Query struct {
+	Terms []QueryTerm
+}
+
+// QueryTerm is one condition of a query
+type QueryTerm struct {
+	Field  string // Empty for bare words
+	Op     string // ":", ">", ">=", "<" or "<="; empty for bare words
+	Value  string
+	Negate bool
+
+	match func(tx *Transaction) bool
+}
+
+// queryFieldAliases maps alternative field names to the canonical ones
+var queryFieldAliases = map[string]string{
+	"cat":         "category",
+	"description": "desc",
+	"location":    "loc",
+	"cur":         "currency",
+}
+
+// ParseQuery parses a filter expression
+func ParseQuery(s string) (Query, error) {
+	tokens, err := splitQuery(ToCDB(s))
+	if err != nil {
+		return Query{}, err
+	}
+
+	var q Query
+	for _, tok := range tokens {
+		term, err := parseQueryTerm(tok)
+		if err != nil {
+			return Query{}, err
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q, nil
+}
+
+// splitQuery splits an expression on whitespace outside double quotes
+func splitQuery(s string) ([]string, error) {
+	var tokens []string
+	var tok strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			tok.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '　'):
+			if tok.Len() > 0 {
+				tokens = append(tokens, tok.String())
+				tok.Reset()
+			}
+		default:
+			tok.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if tok.Len() > 0 {
+		tokens = append(tokens, tok.String())
+	}
+	return tokens, nil
+}
+
+// parseQueryTerm parses and compiles a single term
+func parseQueryTerm(tok string) (QueryTerm, error) {
+	var t QueryTerm
+	if len(tok) > 1 && tok[0] == '-' {
+		t.Negate = true
+		tok = tok[1:]
+	}
+
+	switch from, to, isRange := strings.Cut(tok, ".."); {
+	case strings.HasPrefix(tok, ">") || strings.HasPrefix(tok, "<"):
+		// Bare amount bound
+		t.Field = "amount"
+		t.Op, t.Value = cutQueryOp(tok)
+
+	case isRange && !strings.Contains(tok, ":") && !strings.HasPrefix(tok, `"`):
+		// Bare range; dates have separators after their first character
+		isDate := func(s string) bool { return len(s) > 1 && strings.ContainsAny(s[1:], "-/") }
+		t.Field, t.Op, t.Value = "amount", ":", tok
+		if isDate(from) || isDate(to) {
+			t.Field = "date"
+		}
+
+	default:
+		if len(tok) > 1 && tok[0] == '#' {
+			t.Field, t.Op, t.Value = "tag", ":", tok[1:]
+			break
+		}
+
+		// A field is a word followed by an operator
+		i := strings.IndexAny(tok, ":=<>")
+		field := strings.ToLower(tok[:max(i, 0)])
+		if i <= 0 || strings.IndexFunc(field, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+			t.Value = tok
+			break
+		}
+		t.Field = field
+		t.Op, t.Value = cutQueryOp(tok[i:])
+	}
+
+	t.Value = strings.Trim(t.Value, `"`)
+	if err := t.compile(); err != nil {
+		return QueryTerm{}, err
+	}
+	return t, nil
+}
+
+// cutQueryOp splits the comparison operator off the front of s. "=" is
+// the same as ":".
+func cutQueryOp(s string) (op, rest string) {
+	for _, op := range []string{">=", "<=", ">", "<", ":", "="} {
+		if strings.HasPrefix(s, op) {
+			if op == "=" {
+				return ":", s[1:]
+			}
+			return op, s[len(op):]
+		}
+	}
+	return "", s
+}
+
+// compile validates a term and builds its predicate
+func (t *QueryTerm) compile() error {
+	if name, ok := queryFieldAliases[t.Field]; ok {
+		t.Field = name
+	}
+	if t.Value == "" {
+		return fmt.Errorf("%s%s needs a value", t.Field, t.Op)
+	}
+
+	switch t.Field {
+	case "amount":
+		return t.compileAmount()
+	case "date":
+		return t.compileDate()
+	case "":
+		t.match = containsText(t.Value, func(tx *Transaction) string { return txDescription(tx) + " " + tx.TxnLoc })
+		return nil
+	}
+
+	if t.Op != ":" {
+		return fmt.Errorf("%s only supports %s:", t.Field, t.Field)
+	}
+	switch t.Field {
+	case "desc":
+		t.match = containsText(t.Value, txDescription)
+	case "loc":
+		t.match = containsText(t.Value, func(tx *Transaction) string { return tx.TxnLoc })
+	case "merchant":
+		t.match = containsText(t.Value, func(tx *Transaction) string { return tx.Merchant })
+	case "note":
+		t.match = containsText(t.Value, func(tx *Transaction) string { return tx.Note })
+
+	case "category":
+		t.Value = NormalizeCategory(t.Value)
+		cat := strings.ToLower(t.Value)
+		all := strings.EqualFold(t.Value, CategoryAll)
+		t.match = func(tx *Transaction) bool {
+			return all || CategoryMatches(strings.ToLower(tx.Category), cat)
+		}
+
+	case "currency":
+		t.Value = strings.ToUpper(t.Value)
+		cur := t.Value
+		t.match = func(tx *Transaction) bool {
+			for _, c := range []string{tx.BilledAmount.Currency, tx.OriginalAmount.Currency} {
+				if c == cur || currencyLabel(c) == cur {
+					return true
+				}
+			}
+			return false
+		}
+
+	case "card":
+		digits := t.Value
+		t.match = func(tx *Transaction) bool {
+			return strings.HasSuffix(tx.CardNo, digits) || tx.ApplePayCardLast4 == digits
+		}
+
+	case "tag":
+		tags := ParseTags(t.Value)
+		if len(tags) != 1 {
+			return fmt.Errorf("invalid tag %q", t.Value)
+		}
+		t.Value = tags[0]
+		tag := t.Value
+		t.match = func(tx *Transaction) bool { return tx.HasTag(tag) }
+
+	default:
+		return fmt.Errorf("unknown field %q", t.Field)
+	}
+	return nil
+}
+
+// compileAmount builds the predicate of an amount comparison or range
+func (t *QueryTerm) compileAmount() error {
+	if from, to, ok := strings.Cut(t.Value, ".."); ok && t.Op == ":" {
+		min, err := ParseMoney(from, "")
+		if err != nil {
+			return err
+		}
+		max, err := ParseMoney(to, "")
+		if err != nil {
+			return err
+		}
+		t.match = func(tx *Transaction) bool {
+			return (from == "" || tx.BilledAmount.Cmp(min) >= 0) && (to == "" || tx.BilledAmount.Cmp(max) <= 0)
+		}
+		return nil
+	}
+
+	amount, err := ParseMoney(t.Value, "")
+	if err != nil {
+		return err
+	}
+	op := t.Op
+	t.match = func(tx *Transaction) bool { return compareOp(op, tx.BilledAmount.Cmp(amount)) }
+	return nil
+}
+
+// compileDate builds the predicate of a date comparison or range. Each
+// bound is a day, a month or a year; undated transactions never match.
+func (t *QueryTerm) compileDate() error {
+	var start, end time.Time
+	if from, to, ok := strings.Cut(t.Value, ".."); ok && t.Op == ":" {
+		var err error
+		if from != "" {
+			if start, _, err = parseDatePeriod(from); err != nil {
+				return err
+			}
+		}
+		if to != "" {
+			if _, end, err = parseDatePeriod(to); err != nil {
+				return err
+			}
+		}
+	} else {
+		var err error
+		if start, end, err = parseDatePeriod(t.Value); err != nil {
+			return err
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("date range %s ends before it starts", t.Value)
+	}
+
+	op := t.Op
+	t.match = func(tx *Transaction) bool {
+		d := tx.TransactionDate
+		if d.IsZero() {
+			return false
+		}
+		switch op {
+		case ">":
+			return d.After(end)
+		case ">=":
+			return !d.Before(start)
+		case "<":
+			return d.Before(start)
+		case "<=":
+			return !d.After(end)
+		}
+		return (start.IsZero() || !d.Before(start)) && (end.IsZero() || !d.After(end))
+	}
+	return nil
+}
+
+// parseDatePeriod parses a day, a month such as 2025-03 or a year such as
+// 2025 into its first and last day
+func parseDatePeriod(s string) (time.Time, time.Time, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' })
+	switch {
+	case len(parts) == 1 && len(s) == 4:
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid year %q", s)
+		}
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1), nil
+
+	case len(parts) == 2:
+		year, err := parseYear(parts[0])
+		month, errMonth := strconv.Atoi(parts[1])
+		if err != nil || errMonth != nil || month < 1 || month > 12 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q", s)
+		}
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1), nil
+	}
+
+	day, err := ParseDate(s, 0)
+	return day, day, err
+}
+
+// compareOp applies a comparison operator to the result of a Cmp
+func compareOp(op string, cmp int) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// containsText matches text containing value, ignoring case and full-width
+// characters
+func containsText(value string, text func(tx *Transaction) string) func(tx *Transaction) bool {
+	needle := strings.ToUpper(ToCDB(value))
+	return func(tx *Transaction) bool {
+		return strings.Contains(strings.ToUpper(ToCDB(text(tx))), needle)
+	}
+}
+
+// txDescription returns the half-width description of a transaction
+func txDescription(tx *Transaction) string {
+	if tx.NormalizedDescription != "" {
+		return tx.NormalizedDescription
+	}
+	return ToCDB(tx.Description)
+}
+
+// Matches reports whether a transaction satisfies every term
+func (q Query) Matches(tx Transaction) bool {
+	for _, t := range q.Terms {
+		if t.match(&tx) == t.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// And returns a query matching transactions that match both queries
+func (q Query) And(o Query) Query {
+	terms := make([]QueryTerm, 0, len(q.Terms)+len(o.Terms))
+	return Query{Terms: append(append(terms, q.Terms...), o.Terms...)}
+}
+
+// IsZero reports whether the query matches everything
+func (q Query) IsZero() bool {
+	return len(q.Terms) == 0
+}
+
+// Category returns the category the query is restricted to, or All
+func (q Query) Category() string {
+	if v := q.value("category"); v != "" {
+		return v
+	}
+	return CategoryAll
+}
+
+// WithCategory returns the query restricted to a category instead of the
+// current one; All removes the restriction
+func (q Query) WithCategory(cat string) Query {
+	if cat == CategoryAll {
+		cat = ""
+	}
+	return q.with("category", cat)
+}
+
+// Tag returns the tag the query is restricted to, if any
+func (q Query) Tag() string {
+	return q.value("tag")
+}
+
+// WithTag returns the query restricted to a tag instead of the current
+// one; an empty tag removes the restriction
+func (q Query) WithTag(tag string) Query {
+	return q.with("tag", tag)
+}
+
+// value returns the value of the first positive term on a field
+func (q Query) value(field string) string {
+	for _, t := range q.Terms {
+		if t.Field == field && !t.Negate {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// with replaces the positive terms on a field by one for value
+func (q Query) with(field, value string) Query {
+	var terms []QueryTerm
+	for _, t := range q.Terms {
+		if t.Field != field || t.Negate {
+			terms = append(terms, t)
+		}
+	}
+	if value != "" {
+		t := QueryTerm{Field: field, Op: ":", Value: value}
+		if err := t.compile(); err == nil {
+			terms = append(terms, t)
+		}
+	}
+	return Query{Terms: terms}
+}
+
+// String renders a term in the syntax ParseQuery accepts
+func (t QueryTerm) String() string {
+	s := t.Value
+	if strings.ContainsAny(s, " \t") {
+		s = `"` + s + `"`
+	}
+	if t.Field != "" {
+		s = t.Field + t.Op + s
+	}
+	if t.Negate {
+		s = "-" + s
+	}
+	return s
+}
+
+// String renders the query in the syntax ParseQuery accepts
+func (q Query) String() string {
+	terms := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		terms[i] = t.String()
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// queryTransactions are the transactions the query tests filter, named by
+// their descriptions
+func queryTransactions() []Transaction {
+	coffee := journalTx("STARBUCKS COFFEE", 150, "2025-03-14", "2025-03-15")
+	coffee.Category = "Food > Coffee"
+	coffee.TxnLoc = "TAIPEI"
+	coffee.Merchant = "STARBUCKS"
+	coffee.Tags = []string{"work"}
+
+	uber := journalTx("UBER *TRIP", 620, "2025-04-02", "2025-04-03")
+	uber.Category = CategoryTransport
+	uber.TxnLoc = "AMSTERDAM"
+	uber.OriginalAmount = Money{Units: 18 * moneyScale, Currency: "EUR"}
+	uber.CardNo = "5678"
+
+	hotel := journalTx("HOTEL OKURA", 12000, "2024-12-30", "2025-01-02")
+	hotel.Category = CategoryTravel
+	hotel.Note = "Conference stay"
+
+	refund := journalTx("STARBUCKS REFUND", -150, "2025-03-31", "2025-04-01")
+	refund.Category = "Food > Coffee"
+
+	undated := journalTx("ANNUAL FEE", 500, "", "")
+	undated.Category = CategoryOther
+
+	return []Transaction{coffee, uber, hotel, refund, undated}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string // Descriptions of the matching transactions
+	}{
+		{"", []string{"STARBUCKS COFFEE", "UBER *TRIP", "HOTEL OKURA", "STARBUCKS REFUND", "ANNUAL FEE"}},
+		{"starbucks", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"amsterdam", []string{"UBER *TRIP"}},
+		{"category:Food", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{`category:"Food > Coffee"`, []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"cat:food>coffee", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"category:All", []string{"STARBUCKS COFFEE", "UBER *TRIP", "HOTEL OKURA", "STARBUCKS REFUND", "ANNUAL FEE"}},
+		{"desc:uber", []string{"UBER *TRIP"}},
+		{"loc:taipei", []string{"STARBUCKS COFFEE"}},
+		{"merchant:starbucks", []string{"STARBUCKS COFFEE"}},
+		{`note:"conference stay"`, []string{"HOTEL OKURA"}},
+		{`"hotel okura"`, []string{"HOTEL OKURA"}},
+		{"currency:eur", []string{"UBER *TRIP"}},
+		{"card:678", []string{"UBER *TRIP"}},
+		{"tag:work", []string{"STARBUCKS COFFEE"}},
+		{"#work", []string{"STARBUCKS COFFEE"}},
+
+		// Negation
+		{"-starbucks", []string{"UBER *TRIP", "HOTEL OKURA", "ANNUAL FEE"}},
+		{"-category:Food -#work", []string{"UBER *TRIP", "HOTEL OKURA", "ANNUAL FEE"}},
+		{"-category:Other #work", []string{"STARBUCKS COFFEE"}},
+		{"-amount>500", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND", "ANNUAL FEE"}},
+		{"->500", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND", "ANNUAL FEE"}},
+		{"-100..1000", []string{"HOTEL OKURA", "STARBUCKS REFUND"}},
+		{"-2025-03..2025-04", []string{"HOTEL OKURA", "ANNUAL FEE"}},
+		// Only the first - negates; the rest is text
+		{"--fee", []string{"STARBUCKS COFFEE", "UBER *TRIP", "HOTEL OKURA", "STARBUCKS REFUND", "ANNUAL FEE"}},
+		{"-", nil},
+
+		// Amounts
+		{"amount>500", []string{"UBER *TRIP", "HOTEL OKURA"}},
+		{"amount>=500", []string{"UBER *TRIP", "HOTEL OKURA", "ANNUAL FEE"}},
+		{"amount<0", []string{"STARBUCKS REFUND"}},
+		{"amount<=150", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"amount:620", []string{"UBER *TRIP"}},
+		{"amount=620", []string{"UBER *TRIP"}},
+		{">500", []string{"UBER *TRIP", "HOTEL OKURA"}},
+		{"<=0", []string{"STARBUCKS REFUND"}},
+		{"100..620", []string{"STARBUCKS COFFEE", "UBER *TRIP", "ANNUAL FEE"}},
+		{"1000..", []string{"HOTEL OKURA"}},
+		{"..150", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"amount:-150..0", []string{"STARBUCKS REFUND"}},
+
+		// Dates are days, months or years; undated transactions never match
+		{"date:2025-03-14", []string{"STARBUCKS COFFEE"}},
+		{"date:2025/03/14", []string{"STARBUCKS COFFEE"}},
+		{"date:2025-03", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"date:2024", []string{"HOTEL OKURA"}},
+		{"date:2025-03..2025-04", []string{"STARBUCKS COFFEE", "UBER *TRIP", "STARBUCKS REFUND"}},
+		{"date:2025-04..", []string{"UBER *TRIP"}},
+		{"date:..2024", []string{"HOTEL OKURA"}},
+		{"2025-03-01..2025-03-31", []string{"STARBUCKS COFFEE", "STARBUCKS REFUND"}},
+		{"date>2025-03", []string{"UBER *TRIP"}},
+		{"date>=2025-03-31", []string{"UBER *TRIP", "STARBUCKS REFUND"}},
+		{"date<2025", []string{"HOTEL OKURA"}},
+		{"date<=2025-03-14", []string{"STARBUCKS COFFEE", "HOTEL OKURA"}},
+
+		// Full-width text, operators and spaces fold to half-width
+		{"ＳＴＡＲＢＵＣＫＳ　ＣＯＦＦＥＥ", []string{"STARBUCKS COFFEE"}},
+		{"ｄｅｓｃ：ｕｂｅｒ", []string{"UBER *TRIP"}},
+		{"＞５００　－ｄａｔｅ：２０２４", []string{"UBER *TRIP"}},
+
+		// Several terms must all match
+		{"category:Food amount>0 date:2025-03", []string{"STARBUCKS COFFEE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := ParseQuery(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.expr, err)
+			}
+			var got []string
+			for _, tx := range queryTransactions() {
+				if q.Matches(tx) {
+					got = append(got, tx.Description)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q matches %q, want %q", tt.expr, got, tt.want)
+			}
+
+			again, err := ParseQuery(q.String())
+			if err != nil || again.String() != q.String() {
+				t.Errorf("%q renders as %q, which parses as %q (%v)", tt.expr, q.String(), again.String(), err)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, expr := range []string{
+		`desc:"unterminated`,
+		"desc:",
+		"amount>",
+		"amount>lots",
+		"amount:1.23456",
+		"amount:1..x",
+		">1..2",
+		"desc>5",
+		"colour:red",
+		"date:2025-13",
+		"date:2025-02-30",
+		"date:20250",
+		"date:2025-05..2025-03",
+		"date>soon",
+		"tag:#",
+		"-desc:",
+	} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("ParseQuery(%q) accepted an invalid expression", expr)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report is everything the HTML report shows
+type Report struct {
+	Generated  time.Time
+	Summary    Summary
+	Statements []StatementInfo
+	Charts     []template.HTML // Inline SVG category charts
+	Rows       []TransactionRow
+	categories CategorySet
+}
+
+// BuildReport gathers the summary, the statement totals, the category
+// charts and every transaction row of the statements view
+func BuildReport(statements []Statement, categorized CategorizedTransactions, categories CategorySet) Report {
+	r := Report{
+		Generated:  time.Now(),
+		Summary:    BuildSummary(statements, categorized, categories),
+		categories: categories,
+	}
+	for i, stmt := range statements {
+		r.Statements = append(r.Statements, NewStatementInfo(stmt))
+		r.Rows = append(r.Rows, StatementRows(statements, i, Query{}, sortByDate)...)
+	}
+
+	// One chart of each kind per currency that has purchases
+	currencies := Totals{}
+	for _, total := range r.Summary.Categories {
+		for c := range total.Totals {
+			currencies[c] = Money{}
+		}
+	}
+	for _, currency := range currencies.Currencies() {
+		r.Charts = append(r.Charts, categoryChart(r.Summary.Categories, categories, currency))
+		r.Charts = append(r.Charts, statementChart(statements, categories, currency))
+	}
+	return r
+}
+
+// chartBar is one bar of a chart: a label and its coloured segments
+type chartBar struct {
+	label    string
+	segments []chartSegment
+}
+
+// chartSegment is a part of a bar
+type chartSegment struct {
+	name  string
+	color string
+	value Money
+}
+
+// categoryChart draws the purchases of each top-level category in one
+// currency as horizontal bars
+func categoryChart(totals []CategoryTotal, categories CategorySet, currency string) template.HTML {
+	var bars []chartBar
+	for _, total := range totals {
+		if amount := total.Totals[currency]; CategoryDepth(total.Category) == 0 && amount.Sign() > 0 {
+			name := categories.Label(total.Category)
+			bars = append(bars, chartBar{name, []chartSegment{{name, categories.Color(total.Category), amount}}})
+		}
+	}
+	return svgBarChart("Spending by category, "+currencyLabel(currency), bars)
+}
+
+// statementChart draws the purchases of each statement in one currency as
+// bars split by top-level category
+func statementChart(statements []Statement, categories CategorySet, currency string) template.HTML {
+	var bars []chartBar
+	for _, stmt := range statements {
+		var purchases []Transaction
+		for _, tx := range stmt.Transactions {
+			if tx.BilledAmount.Sign() > 0 && tx.BilledAmount.Currency == currency {
+				purchases = append(purchases, tx)
+			}
+		}
+		bar := chartBar{label: fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo)}
+		for _, total := range categories.RollUp(purchases) {
+			if CategoryDepth(total.Category) == 0 {
+				bar.segments = append(bar.segments, chartSegment{categories.Label(total.Category), categories.Color(total.Category), total.Totals[currency]})
+			}
+		}
+		bars = append(bars, bar)
+	}
+	return svgBarChart("Category breakdown per statement, "+currencyLabel(currency), bars)
+}
+
+// svgBarChart draws labelled horizontal bars, each made of segments, with
+// a tooltip per segment and the bar total at its end
+func svgBarChart(title string, bars []chartBar) template.HTML {
+	const labelWidth, barWidth, totalWidth, rowHeight, top = 160, 480, 140, 24, 30
+
+	largest := int64(0)
+	for _, bar := range bars {
+		sum := int64(0)
+		for _, seg := range bar.segments {
+			sum += seg.value.Units
+		}
+		largest = max(largest, sum)
+	}
+
+	esc := template.HTMLEscapeString
+	var b strings.Builder
+	height := top + rowHeight*len(bars) + 10
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s">`,
+		labelWidth+barWidth+totalWidth, height, labelWidth+barWidth+totalWidth, height, esc(title))
+	fmt.Fprintf(&b, `<text x="0" y="18" class="chart-title">%s</text>`, esc(title))
+	for i, bar := range bars {
+		y := top + i*rowHeight
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, labelWidth-8, y+16, esc(bar.label))
+
+		x, sum := float64(labelWidth), Money{}
+		for _, seg := range bar.segments {
+			width := 0.0
+			if largest > 0 {
+				width = float64(seg.value.Units) / float64(largest) * barWidth
+			}
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"><title>%s: %s</title></rect>`,
+				x, y+3, width, rowHeight-6, esc(ansiColorHex(seg.color)), esc(seg.name), esc(seg.value.Display()))
+			x += width
+			sum = sum.Add(seg.value)
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`, x+6, y+16, esc(sum.Display()))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// ansiColors are the hex values of the 16 basic ANSI colours
+var ansiColors = [16]string{
+	"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+	"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// hexColor matches a three or six digit hex colour code
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// defaultChartColor is used for colours that are neither ANSI numbers nor
+// hex codes
+const defaultChartColor = "#8a8a8a"
+
+// ansiColorHex converts a category colour, an ANSI 256 colour number or a
+// hex code, to a hex code for HTML
+func ansiColorHex(color string) string {
+	if strings.HasPrefix(color, "#") {
+		if !hexColor.MatchString(color) {
+			return defaultChartColor
+		}
+		return color
+	}
+	n, err := strconv.Atoi(color)
+	switch {
+	case err != nil || n < 0 || n > 255:
+		return defaultChartColor
+	case n < 16:
+		return ansiColors[n]
+	case n >= 232:
+		v := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+
+	// 6x6x6 colour cube
+	level := func(i int) int {
+		if i == 0 {
+			return 0
+		}
+		return 55 + i*40
+	}
+	n -= 16
+	return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+}
+
+// WriteHTML renders the report as a single self-contained HTML page
+func (r Report) WriteHTML(w io.Writer) error {
+	funcs := template.FuncMap{
+		"label": r.categories.Label,
+		"depth": CategoryDepth,
+		"date": func(row TransactionRow) string {
+			return formatDate(row.Date, row.RawDate)
+		},
+		"isodate": func(row TransactionRow) string {
+			if row.Date.IsZero() {
+				return ""
+			}
+			return row.Date.Format("2006-01-02")
+		},
+		"day": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.Format(displayDateLayout)
+		},
+	}
+	t, err := template.New("report").Funcs(funcs).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, r)
+}
+
+// reportTemplate is the HTML report page. It loads nothing from the
+// network: styles, charts and the table sorting script are all inline.
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Statement Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 1100px; padding: 0 1rem; color: #222; }
+h1 { color: #c0307a; }
+h2 { color: #1a8a7a; border-bottom: 1px solid #ddd; padding-bottom: .2rem; margin-top: 2.5rem; }
+table { border-collapse: collapse; width: 100%; font-size: .9rem; }
+th, td { padding: .3rem .6rem; border-bottom: 1px solid #eee; text-align: left; vertical-align: top; }
+th { background: #f6f6f6; }
+td.num, th.num { text-align: right; white-space: nowrap; }
+.sortable th { cursor: pointer; user-select: none; }
+.sortable th[aria-sort=ascending]::after { content: " ▲"; }
+.sortable th[aria-sort=descending]::after { content: " ▼"; }
+.muted { color: #777; }
+.chart { display: block; max-width: 100%; height: auto; margin: 1rem 0; font-size: 12px; }
+.chart-title { font-weight: bold; font-size: 14px; }
+.tag { color: #1a6fb0; }
+</style>
+</head>
+<body>
+<h1>Statement Report</h1>
+<p class="muted">Generated {{.Generated.Format "2006-01-02 15:04"}} from {{.Summary.Statements}} statements.</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Group</th><th class="num">Transactions</th><th class="num">Total</th></tr>
+<tr><td>Apple Pay</td><td class="num">{{.Summary.ApplePay.Count}}</td><td class="num">{{.Summary.ApplePay.Totals}}</td></tr>
+<tr><td>PayPal</td><td class="num">{{.Summary.PayPal.Count}}</td><td class="num">{{.Summary.PayPal.Totals}}</td></tr>
+<tr><td>LINE Pay</td><td class="num">{{.Summary.LinePay.Count}}</td><td class="num">{{.Summary.LinePay.Totals}}</td></tr>
+<tr><td>Jkopay</td><td class="num">{{.Summary.Jkopay.Count}}</td><td class="num">{{.Summary.Jkopay.Totals}}</td></tr>
+<tr><td>Foreign Fees</td><td class="num">{{.Summary.ForeignFees.Count}}</td><td class="num">{{.Summary.ForeignFees.Totals}}</td></tr>
+<tr><td>Other</td><td class="num">{{.Summary.Other.Count}}</td><td class="num">{{.Summary.Other.Totals}}</td></tr>
+</table>
+{{if .Summary.ApplePayCards}}
+<h3>Apple Pay by Card</h3>
+<table>
+<tr><th>Card</th><th class="num">Transactions</th><th class="num">Total</th></tr>
+{{range .Summary.ApplePayCards}}<tr><td>{{if eq .Card "Unknown"}}Unknown{{else}}Ending in {{.Card}}{{end}}</td><td class="num">{{.Count}}</td><td class="num">{{.Totals}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<h2>Statements</h2>
+<table>
+<tr><th>Statement</th><th>Statement Date</th><th>Due</th><th class="num">Total</th><th class="num">Minimum</th><th class="num">Transactions</th></tr>
+{{range .Statements}}<tr><td>{{.Period}}</td><td>{{day .Date}}</td><td>{{day .PaymentDue}}</td><td class="num">{{.Total.Display}}</td><td class="num">{{.MinPayment.Display}}</td><td class="num">{{.Transactions}}</td></tr>
+{{end}}</table>
+
+<h2>Spending by Category</h2>
+{{range .Charts}}{{.}}
+{{end}}
+<table>
+<tr><th>Category</th><th class="num">Transactions</th><th class="num">Total</th></tr>
+{{range .Summary.Categories}}<tr><td><span style="padding-left: {{depth .Category}}em">{{label .Category}}</span></td><td class="num">{{.Count}}</td><td class="num">{{.Totals}}</td></tr>
+{{end}}</table>
+{{if .Summary.Tags}}
+<h3>Tags</h3>
+<table>
+<tr><th>Tag</th><th class="num">Transactions</th><th class="num">Total</th></tr>
+{{range .Summary.Tags}}<tr><td class="tag">#{{.Tag}}</td><td class="num">{{.Count}}</td><td class="num">{{.Totals}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<h2>Busiest Days</h2>
+<table>
+<tr><th>Day</th><th class="num">Transactions</th><th class="num">Total</th></tr>
+{{range .Summary.BusiestDays}}<tr><td>{{day .Date}}</td><td class="num">{{.Count}}</td><td class="num">{{.Totals}}</td></tr>
+{{end}}</table>
+
+<h2>Transactions</h2>
+<p class="muted">Click a column heading to sort.</p>
+<table class="sortable" id="transactions">
+<thead><tr><th>Date</th><th>Statement</th><th>Category</th><th class="num" data-type="number">Amount</th><th>Description</th><th>Location</th><th>Merchant</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td data-sort="{{isodate .}}">{{date .}}</td><td>{{.Period}}</td><td>{{label .Category}}</td><td class="num" data-sort="{{.Billed.String}}">{{.Billed.Display}}</td><td>{{.Description}}{{range .Tags}} <span class="tag">#{{.}}</span>{{end}}{{if .Note}}<br><span class="muted">{{.Note}}</span>{{end}}</td><td>{{.Location}}</td><td>{{.Merchant}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  var headers = table.querySelectorAll("th");
+  headers.forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var ascending = th.getAttribute("aria-sort") !== "ascending";
+      var numeric = th.dataset.type === "number";
+      headers.forEach(function (h) { h.removeAttribute("aria-sort"); });
+      th.setAttribute("aria-sort", ascending ? "ascending" : "descending");
+
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var key = function (row) {
+        var cell = row.cells[col];
+        var value = cell.dataset.sort !== undefined ? cell.dataset.sort : cell.textContent;
+        return numeric ? parseFloat(value) || 0 : value.toLowerCase();
+      };
+      rows.sort(function (a, b) {
+        var x = key(a), y = key(b);
+        var order = numeric ? x - y : x.localeCompare(y);
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
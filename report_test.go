@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestANSIColorHex(t *testing.T) {
+	tests := []struct {
+		color, want string
+	}{
+		{"#ff8800", "#ff8800"},
+		{"#F80", "#F80"},
+		{"9", "#ff0000"},
+		{"214", "#ffaf00"},
+		{"244", "#808080"},
+		{"", defaultChartColor},
+		{"256", defaultChartColor},
+		{"#ff88", defaultChartColor},
+		{"#zzzzzz", defaultChartColor},
+		{`#fff" onmouseover="alert(1)`, defaultChartColor},
+		{"#fff><script>alert(1)</script>", defaultChartColor},
+	}
+	for _, tt := range tests {
+		if got := ansiColorHex(tt.color); got != tt.want {
+			t.Errorf("ansiColorHex(%q) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestSVGBarChartEscapesColor(t *testing.T) {
+	chart := string(svgBarChart("Totals", []chartBar{{
+		label:    "<b>March</b>",
+		segments: []chartSegment{{name: "Food", color: `#fff"/><script>alert(1)</script>`, value: twd(100)}},
+	}}))
+	if strings.Contains(chart, "<script>") || strings.Contains(chart, "<b>") {
+		t.Errorf("chart is not escaped:\n%s", chart)
+	}
+	if !strings.Contains(chart, `fill="`+defaultChartColor+`"`) {
+		t.Errorf("chart does not fall back to the default colour:\n%s", chart)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TransactionRow is a row of the transactions table: one transaction, or
+// the foreign transaction fees of a statement added up
+type TransactionRow struct {
+	Statement   int         `json:"-"` // Index of the statement
+	Transaction Transaction `json:"-"` // Zero for the foreign fee row
+	RawDate     string      `json:"-"` // Date as it appears on the statement
+
+	Period       string    `json:"statement"` // Statement year and month, e.g. 2025/03
+	Date         time.Time `json:"date"`
+	Category     string    `json:"category"`
+	Billed       Money     `json:"billed"`
+	Original     Money     `json:"original"`
+	Description  string    `json:"description"` // Clean description
+	Location     string    `json:"location,omitempty"`
+	Card         string    `json:"card,omitempty"`
+	ApplePayCard string    `json:"applePayCard,omitempty"`
+	Merchant     string    `json:"merchant,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	Foreign      bool      `json:"foreign,omitempty"`     // Foreign transaction
+	ForeignFees  int       `json:"foreignFees,omitempty"` // Number of fees added up in a foreign fee row
+}
+
+// IsFee reports whether the row adds up the foreign fees of a statement
+func (r TransactionRow) IsFee() bool {
+	return r.ForeignFees > 0
+}
+
+// newTransactionRow builds the row of a single transaction
+func newTransactionRow(statements []Statement, idx int, tx Transaction) TransactionRow {
+	stmt := statements[idx]
+	return TransactionRow{
+		Statement:    idx,
+		Transaction:  tx,
+		RawDate:      tx.TxnDate,
+		Period:       fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo),
+		Date:         tx.TransactionDate,
+		Category:     tx.Category,
+		Billed:       tx.BilledAmount,
+		Original:     tx.OriginalAmount,
+		Description:  GetCleanDescription(tx.NormalizedDescription),
+		Location:     tx.TxnLoc,
+		Card:         tx.CardNo,
+		ApplePayCard: tx.ApplePayCardLast4,
+		Merchant:     tx.Merchant,
+		Tags:         tx.Tags,
+		Note:         tx.Note,
+		Foreign:      tx.IsForeignTxn,
+	}
+}
+
+// StatementRows builds the rows of one statement as the transactions table
+// shows them: the transactions passing the filter in sort order, then the
+// foreign fees added up into one row when nothing is filtered out. Online
+// bank payments are left out.
+func StatementRows(statements []Statement, idx int, filter Query, sortBy sortMode) []TransactionRow {
+	stmt := statements[idx]
+
+	// Separate foreign fees from other transactions
+	var regularTxs []Transaction
+	var foreignFeeTxs []Transaction
+	foreignFeeTotal := Money{Currency: stmt.Currency}
+
+	for _, tx := range stmt.Transactions {
+		normalizedDesc := ToCDB(tx.Description)
+		if strings.HasPrefix(normalizedDesc, "國外交易手續費") {
+			foreignFeeTxs = append(foreignFeeTxs, tx)
+			foreignFeeTotal = foreignFeeTotal.Add(tx.BilledAmount)
+		} else if tx.NormalizedDescription != "網路銀行繳款" && filter.Matches(tx) {
+			regularTxs = append(regularTxs, tx)
+		}
+	}
+
+	rows := make([]TransactionRow, 0, len(regularTxs)+1)
+	for _, tx := range regularTxs {
+		rows = append(rows, newTransactionRow(statements, idx, tx))
+	}
+	sortRows(rows, sortBy)
+
+	// Add aggregated foreign fee row if there are any (only without filters)
+	if len(foreignFeeTxs) > 0 && filter.IsZero() {
+		fee := TransactionRow{
+			Statement:   idx,
+			RawDate:     stmt.StmtDate,
+			Period:      fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo),
+			Date:        stmt.StatementDate,
+			Category:    "Fee",
+			Billed:      foreignFeeTotal,
+			Original:    foreignFeeTotal,
+			Description: fmt.Sprintf("Foreign TX Fee (%d)", len(foreignFeeTxs)),
+			ForeignFees: len(foreignFeeTxs),
+		}
+		if first := foreignFeeTxs[0]; !first.TransactionDate.IsZero() {
+			fee.Date, fee.RawDate = first.TransactionDate, first.TxnDate
+		}
+		rows = append(rows, fee)
+	}
+
+	return rows
+}
+
+// MatchingRows builds the rows of every transaction in every statement
+// that matches a query, in sort order. Foreign fees are kept as they are.
+func MatchingRows(statements []Statement, query Query, sortBy sortMode) []TransactionRow {
+	var rows []TransactionRow
+	for i, stmt := range statements {
+		for _, tx := range stmt.Transactions {
+			if tx.NormalizedDescription != "網路銀行繳款" && query.Matches(tx) {
+				rows = append(rows, newTransactionRow(statements, i, tx))
+			}
+		}
+	}
+	sortRows(rows, sortBy)
+	return rows
+}
+
+// sortRows orders transaction rows by a sort mode, keeping the statement
+// order for equal keys
+func sortRows(rows []TransactionRow, sortBy sortMode) {
+	less := sortBy.less()
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(rows[i].Transaction, rows[j].Transaction)
+	})
+}
+
+// less returns the ordering of transactions for a sort mode
+func (s sortMode) less() func(a, b Transaction) bool {
+	switch s {
+	case sortByAmount:
+		return func(a, b Transaction) bool { return a.BilledAmount.Cmp(b.BilledAmount) > 0 }
+	case sortByLocation:
+		return func(a, b Transaction) bool { return a.TxnLoc < b.TxnLoc }
+	case sortByCategory:
+		return func(a, b Transaction) bool { return a.Category < b.Category }
+	case sortByMerchant:
+		return func(a, b Transaction) bool { return a.Merchant < b.Merchant }
+	default:
+		return func(a, b Transaction) bool { return a.TransactionDate.Before(b.TransactionDate) }
+	}
+}
+
+// WriteRows prints transaction rows as aligned text followed by their count
+// and total
+func WriteRows(w io.Writer, rows []TransactionRow) {
+	// Right-align the amounts, tabwriter aligns the rest
+	amountWidth := 0
+	for _, row := range rows {
+		amountWidth = max(amountWidth, len(row.Billed.Display()))
+	}
+
+	totals := Totals{}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		desc := row.Description
+		if len(row.Tags) > 0 {
+			desc += " " + FormatTags(row.Tags)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatDate(row.Date, row.RawDate), row.Period, row.Category,
+			rightPadAmount(row.Billed.Display(), amountWidth), desc, row.Location)
+		totals.Add(row.Billed)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d transactions, Total: %s\n", len(rows), totals)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RuleStats counts how often a rule matched a transaction and how often it
+// was the first match, the one that decides the category
+type RuleStats struct {
+	Rule    Rule
+	Index   int  // Position in the categorizer, from 0
+	User    bool // Rule came from the user's rules file
+	Matches int
+	Wins    int
+}
+
+// RuleConflict is a description matched by rules for unrelated categories
+type RuleConflict struct {
+	Description string   // Clean description
+	Categories  []string // Categories of the matching rules, in rule order
+	Rules       []int    // Index of the first matching rule for each category
+	Count       int      // Number of transactions with this description
+}
+
+// RuleCheck is the result of running the rules over a history
+type RuleCheck struct {
+	Transactions int
+	Spend        Totals // Billed spend, purchases only
+	Other        int    // Transactions left in Other
+	OtherSpend   Totals
+	Conflicts    []RuleConflict
+	Rules        []RuleStats
+}
+
+// CheckRules categorizes the statements with the rules alone and reports
+// their coverage, conflicting matches and how often each rule fires
+func CheckRules(statements []Statement, c *Categorizer) RuleCheck {
+	rulesOnly := &Categorizer{Rules: c.Rules, UserRules: c.UserRules}
+	CategorizeTransactions(statements, rulesOnly)
+
+	check := RuleCheck{Spend: Totals{}, OtherSpend: Totals{}}
+	for i, rule := range c.Rules {
+		check.Rules = append(check.Rules, RuleStats{Rule: rule, Index: i, User: i < c.UserRules})
+	}
+
+	conflicts := make(map[string]*RuleConflict)
+	for _, stmt := range statements {
+		for i := range stmt.Transactions {
+			tx := &stmt.Transactions[i]
+			if tx.NormalizedDescription == "網路銀行繳款" {
+				continue
+			}
+			check.Transactions++
+
+			if tx.BilledAmount.Sign() > 0 {
+				check.Spend.Add(tx.BilledAmount)
+			}
+			if tx.Category == CategoryOther {
+				check.Other++
+				if tx.BilledAmount.Sign() > 0 {
+					check.OtherSpend.Add(tx.BilledAmount)
+				}
+			}
+
+			// Every rule that matches, not only the first
+			cleanDesc := GetCleanDescription(tx.NormalizedDescription)
+			var categories []string
+			var ruleIdx []int
+			for j := range c.Rules {
+				if !c.Rules[j].matches(tx, cleanDesc) {
+					continue
+				}
+				stats := &check.Rules[j]
+				stats.Matches++
+				if len(ruleIdx) == 0 {
+					stats.Wins++
+				}
+
+				// A parent and its subcategory do not conflict
+				seen := false
+				for _, cat := range categories {
+					seen = seen || CategoryMatches(cat, c.Rules[j].Category) || CategoryMatches(c.Rules[j].Category, cat)
+				}
+				if !seen {
+					categories = append(categories, c.Rules[j].Category)
+					ruleIdx = append(ruleIdx, j)
+				}
+			}
+
+			if len(categories) > 1 {
+				key := cleanDesc + "\x00" + strings.Join(categories, "\x00")
+				conflict, ok := conflicts[key]
+				if !ok {
+					conflict = &RuleConflict{Description: cleanDesc, Categories: categories, Rules: ruleIdx}
+					conflicts[key] = conflict
+				}
+				conflict.Count++
+			}
+		}
+	}
+
+	for _, conflict := range conflicts {
+		check.Conflicts = append(check.Conflicts, *conflict)
+	}
+	sort.Slice(check.Conflicts, func(i, j int) bool {
+		if check.Conflicts[i].Count != check.Conflicts[j].Count {
+			return check.Conflicts[i].Count > check.Conflicts[j].Count
+		}
+		return check.Conflicts[i].Description < check.Conflicts[j].Description
+	})
+
+	return check
+}
+
+// Unused returns the rules that never decided a category
+func (r RuleCheck) Unused() []RuleStats {
+	var unused []RuleStats
+	for _, stats := range r.Rules {
+		if stats.Wins == 0 {
+			unused = append(unused, stats)
+		}
+	}
+	return unused
+}
+
+// Write prints the report as text
+func (r RuleCheck) Write(w io.Writer) {
+	fmt.Fprintln(w, "Coverage")
+	fmt.Fprintf(w, "  %d transactions, %d left in %s\n", r.Transactions, r.Other, CategoryOther)
+	for _, cur := range r.Spend.Currencies() {
+		spend, other := r.Spend[cur], r.OtherSpend[cur]
+		other.Currency = cur
+		share := 0.0
+		if !spend.IsZero() {
+			share = other.Float64() / spend.Float64() * 100
+		}
+		fmt.Fprintf(w, "  %s of %s spend in %s (%.1f%%)\n", other.Display(), spend.Display(), CategoryOther, share)
+	}
+
+	fmt.Fprintf(w, "\nConflicts (%d)\n", len(r.Conflicts))
+	if len(r.Conflicts) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, conflict := range r.Conflicts {
+		matches := make([]string, len(conflict.Categories))
+		for i, cat := range conflict.Categories {
+			matches[i] = fmt.Sprintf("%s (rule #%d)", cat, conflict.Rules[i]+1)
+		}
+		fmt.Fprintf(w, "  %dx %s: %s\n", conflict.Count, conflict.Description, strings.Join(matches, ", "))
+	}
+
+	unused := r.Unused()
+	fmt.Fprintf(w, "\nRules that never fire (%d of %d)\n", len(unused), len(r.Rules))
+	if len(unused) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, stats := range unused {
+		source := "built-in"
+		if stats.User {
+			source = "user"
+		}
+		note := ""
+		if stats.Matches > 0 {
+			note = fmt.Sprintf(" (matches %d, always shadowed by earlier rules)", stats.Matches)
+		}
+		fmt.Fprintf(w, "  #%d (%s) %s%s\n", stats.Index+1, source, stats.Rule, note)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckRules(t *testing.T) {
+	var rules []Rule
+	for _, r := range []Rule{
+		{Match: MatchContains, Pattern: "PIZZA", Category: CategoryFood},
+		{Match: MatchContains, Pattern: "EXPRESS", Category: CategoryTravel},
+		{Match: MatchPrefix, Pattern: "PIZZA", Category: "Food > Takeaway"},
+		{Match: MatchPrefix, Pattern: "GYM", Category: CategoryUtilities},
+	} {
+		if err := r.compile(); err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	c := &Categorizer{Rules: rules, UserRules: len(rules)}
+
+	statements := []Statement{{Transactions: []Transaction{
+		journalTx("PIZZA EXPRESS STATION", 300, "2025-03-02", ""),
+		journalTx("PIZZA EXPRESS STATION", 250, "2025-03-09", ""),
+		journalTx("PIZZA HUT", 400, "2025-03-10", ""),
+		journalTx("CORNER SHOP", 100, "2025-03-11", ""),
+		journalTx("網路銀行繳款", -5000, "2025-03-12", ""),
+	}}}
+	check := CheckRules(statements, c)
+
+	if check.Transactions != 4 || check.Other != 1 || check.Spend.String() != "NT$1,050.00" || check.OtherSpend.String() != "NT$100.00" {
+		t.Errorf("coverage = %d transactions, %d in Other, spend %s, Other spend %s",
+			check.Transactions, check.Other, check.Spend, check.OtherSpend)
+	}
+
+	// The takeaway rule is a subcategory of the pizza rule, not a conflict
+	want := []RuleConflict{{
+		Description: "PIZZA EXPRESS STATION",
+		Categories:  []string{CategoryFood, CategoryTravel},
+		Rules:       []int{0, 1},
+		Count:       2,
+	}}
+	if !reflect.DeepEqual(check.Conflicts, want) {
+		t.Errorf("Conflicts = %+v, want %+v", check.Conflicts, want)
+	}
+
+	var stats []string
+	for _, s := range check.Rules {
+		stats = append(stats, strings.Join([]string{s.Rule.Pattern, strconv.Itoa(s.Matches), strconv.Itoa(s.Wins)}, " "))
+	}
+	if got := strings.Join(stats, ", "); got != "PIZZA 3 3, EXPRESS 2 0, PIZZA 3 0, GYM 0 0" {
+		t.Errorf("rule stats = %s", got)
+	}
+
+	var unused []int
+	for _, s := range check.Unused() {
+		unused = append(unused, s.Index)
+	}
+	if !reflect.DeepEqual(unused, []int{1, 2, 3}) {
+		t.Errorf("Unused() = %v, want rules 1, 2 and 3", unused)
+	}
+
+	var out strings.Builder
+	check.Write(&out)
+	for _, line := range []string{
+		"2x PIZZA EXPRESS STATION: Food (rule #1), Travel (rule #2)",
+		"Rules that never fire (3 of 4)",
+		"#3 (user) description prefix \"PIZZA\" -> Food > Takeaway (matches 3, always shadowed by earlier rules)",
+		"#4 (user) description prefix \"GYM\" -> Utilities\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule match types
+const (
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+	MatchRegex    = "regex"
+	MatchExact    = "exact"
+)
+
+// Rule fields
+const (
+	FieldDescription = "description"
+	FieldLocation    = "location"
+	FieldCurrency    = "currency"
+	FieldCard        = "card"
+)
+
+// Rule assigns a category to transactions whose field matches a pattern.
+// Matching is case-insensitive; the description is matched after payment
+// provider prefixes are removed.
+type Rule struct {
+	Match     string `json:"match"`               // prefix, contains, regex or exact
+	Field     string `json:"field,omitempty"`     // description (default), location, currency or card
+	Pattern   string `json:"pattern"`             // Text or regular expression to match
+	MinAmount string `json:"minAmount,omitempty"` // Optional inclusive lower bound on the billed amount
+	MaxAmount string `json:"maxAmount,omitempty"` // Optional inclusive upper bound on the billed amount
+	Category  string `json:"category"`
+
+	re       *regexp.Regexp
+	min, max *Money
+}
+
+// rulesFile is the on-disk layout of a rules file
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// compile validates a rule and prepares its pattern and amount bounds
+func (r *Rule) compile() error {
+	if r.Field == "" {
+		r.Field = FieldDescription
+	}
+	switch r.Field {
+	case FieldDescription, FieldLocation, FieldCurrency, FieldCard:
+	default:
+		return fmt.Errorf("unknown field %q", r.Field)
+	}
+
+	r.Category = NormalizeCategory(r.Category)
+	if r.Category == "" {
+		return errors.New("rule has no category")
+	}
+	if r.Pattern == "" {
+		return errors.New("rule has no pattern")
+	}
+
+	switch r.Match {
+	case MatchPrefix, MatchContains, MatchExact:
+		r.Pattern = strings.ToUpper(r.Pattern)
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return err
+		}
+		r.re = re
+	default:
+		return fmt.Errorf("unknown match type %q", r.Match)
+	}
+
+	for _, bound := range []struct {
+		raw string
+		dst **Money
+	}{{r.MinAmount, &r.min}, {r.MaxAmount, &r.max}} {
+		if bound.raw == "" {
+			continue
+		}
+		m, err := ParseMoney(bound.raw, "")
+		if err != nil {
+			return err
+		}
+		*bound.dst = &m
+	}
+
+	return nil
+}
+
+// String describes the rule in the same terms as the rules file
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s %s %q -> %s", r.Field, r.Match, r.Pattern, r.Category)
+	if r.MinAmount != "" || r.MaxAmount != "" {
+		s += fmt.Sprintf(" [amount %s..%s]", r.MinAmount, r.MaxAmount)
+	}
+	return s
+}
+
+// matches reports whether the rule applies to a transaction
+func (r Rule) matches(tx *Transaction, cleanDesc string) bool {
+	_, _, ok := r.match(tx, cleanDesc)
+	return ok
+}
+
+// match applies the rule and, when it matches, returns the value it was
+// tested against and the span of the value that matched. The span covers
+// the whole value for exact matches and rules on fields other than the
+// description.
+func (r Rule) match(tx *Transaction, cleanDesc string) (string, [2]int, bool) {
+	var span [2]int
+	if r.min != nil && tx.BilledAmount.Cmp(*r.min) < 0 {
+		return "", span, false
+	}
+	if r.max != nil && tx.BilledAmount.Cmp(*r.max) > 0 {
+		return "", span, false
+	}
+
+	var value string
+	switch r.Field {
+	case FieldDescription:
+		value = cleanDesc
+	case FieldLocation:
+		value = tx.TxnLoc
+	case FieldCurrency:
+		value = tx.OriginalAmount.Currency
+	case FieldCard:
+		value = tx.CardNo
+	}
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	ok := false
+	switch r.Match {
+	case MatchPrefix:
+		ok = strings.HasPrefix(value, r.Pattern)
+		span = [2]int{0, len(r.Pattern)}
+	case MatchContains:
+		i := strings.Index(value, r.Pattern)
+		ok = i >= 0
+		span = [2]int{i, i + len(r.Pattern)}
+	case MatchExact:
+		ok = value == r.Pattern
+		span = [2]int{0, len(value)}
+	case MatchRegex:
+		if loc := r.re.FindStringIndex(value); loc != nil {
+			ok = true
+			span = [2]int{loc[0], loc[1]}
+		}
+	}
+	return value, span, ok
+}
+
+// LoadRules reads an ordered list of rules from a JSON file
+func LoadRules(filename string) ([]Rule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range file.Rules {
+		if err := file.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", filename, i+1, err)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// LoadUserRules loads the explicit rules file, or rules.json from the
+// configuration directory when it exists
+func LoadUserRules(explicit string) ([]Rule, error) {
+	if explicit != "" {
+		return LoadRules(explicit)
+	}
+
+	path := configPath("rules.json")
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return LoadRules(path)
+}
+
+// defaultRules returns the built-in rule set
+func defaultRules() []Rule {
+	var rules []Rule
+	add := func(match, category string, patterns ...string) {
+		for _, p := range patterns {
+			rule := Rule{Match: match, Pattern: p, Category: category}
+			if err := rule.compile(); err != nil {
+				panic(err)
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	// Transportation
+	add(MatchPrefix, CategoryTransport,
+		"LIME", "UBER", "UBR*", "FREENOW", "ZITY", "FLIXBUS", "FNM*", "FNM ", "TRENITALIA", "TRENORD", "TRAIN", "SCOOTER", "RAILWAY")
+
+	// Food & Groceries
+	add(MatchContains, CategoryFood,
+		"CIBO", "PANIFICIO", "DELIVEROO", "CAFE", "CAFFE", "MACELLERIA",
+		"ESSELUNGA", "MERCATO", "MERCADO", "RISTORANTE", "RESTAURANT", "OSTERIA", "GELATERIA", "GELATO", "GELATI", "PIZZA", "PIZZERIA",
+		"BURGER", "CONAD", "CARREFOUR", "EATALY", "BAR", "TRATTORIA", "DM-",
+		"GLOVO", "KFC", "MCDONALDS", "NESPRESSO", "PASTICCERIA",
+		"PRETAMANGER", "FIVEGUYS", "AUTOGRILL", "STARBUCKS", "DRINK")
+
+	// Shopping
+	add(MatchContains, CategoryShopping,
+		"AMAZON*", "WWW.AMAZON", "DECATHLON", "BRICOCENTER", "TIGROS", "TEMU.COM", "UNIQLO")
+
+	// Travel & Accommodation
+	add(MatchContains, CategoryTravel,
+		"AIRBNB", "ALBERGO", "AIRPORT", "EASYJET", "TRIP.COM", "EVAAIR", "RYANAIR", "FLYSCOOT", "GOTOGATE", "BOOKINGCOM", "HOTEL", "KKDAY", "KIWICOM")
+
+	// Utilities & Services
+	add(MatchContains, CategoryUtilities,
+		"APPLE.COM", "VODAFONE", "AWS", "AMAZONWEBSERVICES", "1PASSWORD", "POLITECNICO", "POSTEITALIA", "OPENAI", "POLISPORTIVA", "PORKBUN")
+
+	return rules
+}
+
+// Categorizer assigns categories using user rules followed by the
+// built-in rules, and optionally a trained classifier for transactions no
+// rule matches
+type Categorizer struct {
+	Rules         []Rule
+	UserRules     int // Number of leading rules that came from the user
+	Classifier    *Classifier
+	MinConfidence float64 // Classifier suggestions below this are left in Other
+}
+
+// NewCategorizer builds a categorizer where user rules take precedence
+// over the built-in set
+func NewCategorizer(userRules []Rule) *Categorizer {
+	rules := make([]Rule, 0, len(userRules))
+	rules = append(rules, userRules...)
+	rules = append(rules, defaultRules()...)
+	return &Categorizer{Rules: rules, UserRules: len(userRules)}
+}
+
+// Detect returns the category of the first matching rule, or CategoryOther
+func (c *Categorizer) Detect(tx *Transaction, cleanDesc string) string {
+	for _, rule := range c.Rules {
+		if rule.matches(tx, cleanDesc) {
+			return rule.Category
+		}
+	}
+	return CategoryOther
+}
+
+// Categorize sets the category of a transaction. Rules are tried first;
+// when none match, the classifier's suggestion is used if it is confident
+// enough and its confidence is recorded on the transaction.
+func (c *Categorizer) Categorize(tx *Transaction, cleanDesc string) {
+	tx.Category = c.Detect(tx, cleanDesc)
+	tx.Confidence = 0
+	if tx.Category != CategoryOther || c.Classifier == nil {
+		return
+	}
+
+	if category, confidence := c.Classifier.Predict(tx, cleanDesc); category != "" && confidence >= c.MinConfidence {
+		tx.Category = category
+		tx.Confidence = confidence
+	}
+}
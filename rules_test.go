@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRuleMatches(t *testing.T) {
+	tx := Transaction{
+		TxnLoc:         "Milano",
+		CardNo:         "4000123412341234",
+		BilledAmount:   twd(450),
+		OriginalAmount: Money{Units: 130000, Currency: "EUR"},
+	}
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"prefix", Rule{Match: MatchPrefix, Pattern: "uber"}, true},
+		{"prefix elsewhere", Rule{Match: MatchPrefix, Pattern: "TRIP"}, false},
+		{"contains", Rule{Match: MatchContains, Pattern: "trip"}, true},
+		{"contains missing", Rule{Match: MatchContains, Pattern: "EATS"}, false},
+		{"exact", Rule{Match: MatchExact, Pattern: "Uber Trip"}, true},
+		{"exact partial", Rule{Match: MatchExact, Pattern: "UBER"}, false},
+		{"regex", Rule{Match: MatchRegex, Pattern: `^ub(e|a)r\s`}, true},
+		{"regex missing", Rule{Match: MatchRegex, Pattern: `^TRIP`}, false},
+		{"location", Rule{Match: MatchExact, Field: FieldLocation, Pattern: "MILANO"}, true},
+		{"location mismatch", Rule{Match: MatchExact, Field: FieldLocation, Pattern: "ROMA"}, false},
+		{"currency", Rule{Match: MatchExact, Field: FieldCurrency, Pattern: "eur"}, true},
+		{"card", Rule{Match: MatchRegex, Field: FieldCard, Pattern: "1234$"}, true},
+		{"card mismatch", Rule{Match: MatchContains, Field: FieldCard, Pattern: "9999"}, false},
+		{"min bound inclusive", Rule{Match: MatchPrefix, Pattern: "UBER", MinAmount: "450"}, true},
+		{"below min", Rule{Match: MatchPrefix, Pattern: "UBER", MinAmount: "450.01"}, false},
+		{"max bound inclusive", Rule{Match: MatchPrefix, Pattern: "UBER", MaxAmount: "450"}, true},
+		{"above max", Rule{Match: MatchPrefix, Pattern: "UBER", MaxAmount: "449.99"}, false},
+		{"within bounds", Rule{Match: MatchPrefix, Pattern: "UBER", MinAmount: "100", MaxAmount: "1,000"}, true},
+	}
+	for _, tt := range tests {
+		rule := tt.rule
+		rule.Category = CategoryTransport
+		if err := rule.compile(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := rule.matches(&tx, "UBER TRIP"); got != tt.want {
+			t.Errorf("%s: %s matches = %v, want %v", tt.name, rule, got, tt.want)
+		}
+	}
+}
+
+func TestUserRulesTakePrecedence(t *testing.T) {
+	user := Rule{Match: MatchContains, Pattern: "STARBUCKS", Category: "Food > Coffee"}
+	if err := user.compile(); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCategorizer([]Rule{user})
+	if c.UserRules != 1 {
+		t.Errorf("UserRules = %d, want 1", c.UserRules)
+	}
+
+	tests := []struct {
+		desc, want string
+	}{
+		{"STARBUCKS MILANO", "Food > Coffee"},
+		{"UBER TRIP", CategoryTransport},
+		{"CORNER SHOP", CategoryOther},
+	}
+	for _, tt := range tests {
+		if got := c.Detect(&Transaction{}, tt.desc); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name, rule, want, err string
+	}{
+		{"valid", `{"match": "prefix", "pattern": "PXMART", "category": "Food>Groceries"}`, "Food > Groceries", ""},
+		{"empty trailing level", `{"match": "prefix", "pattern": "PXMART", "category": "Food > "}`, "Food", ""},
+		{"only separators", `{"match": "prefix", "pattern": "PXMART", "category": " > "}`, "", "no category"},
+		{"invalid regex", `{"match": "regex", "pattern": "(PXMART", "category": "Food"}`, "", "missing closing )"},
+		{"unknown match", `{"match": "suffix", "pattern": "PXMART", "category": "Food"}`, "", "unknown match type"},
+		{"unknown field", `{"match": "prefix", "field": "merchant", "pattern": "PXMART", "category": "Food"}`, "", "unknown field"},
+		{"invalid amount", `{"match": "prefix", "pattern": "PXMART", "minAmount": "ten", "category": "Food"}`, "", "invalid amount"},
+		{"no pattern", `{"match": "prefix", "category": "Food"}`, "", "no pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(`{"rules": [`+tt.rule+`]}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := LoadRules(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), "rule 1: ") || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("LoadRules() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rules[0].Category != tt.want {
+				t.Errorf("category = %q, want %q", rules[0].Category, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// options are the flags shared by the TUI and every subcommand that loads
+// statements
+type options struct {
+	csvProfile    string
+	from, to      string
+	rules         string
+	categories    string
+	overrides     string
+	classifier    string
+	minConfidence float64
+	merchants     string
+}
+
+// register adds the shared flags to a flag set
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.csvProfile, "csv-profile", "", "CSV column mapping profile (JSON)")
+	fs.StringVar(&o.from, "from", "", "only include transactions on or after this date (YYYY-MM-DD)")
+	fs.StringVar(&o.to, "to", "", "only include transactions on or before this date (YYYY-MM-DD)")
+	fs.StringVar(&o.rules, "rules", "", "categorization rules file (JSON)")
+	fs.StringVar(&o.categories, "categories", "", "category definitions file (JSON)")
+	fs.StringVar(&o.overrides, "overrides", "", "per-transaction category overrides file (JSON)")
+	fs.StringVar(&o.classifier, "classifier", "", "trained category classifier (JSON)")
+	fs.Float64Var(&o.minConfidence, "min-confidence", 0.6, "minimum classifier confidence for a suggested category")
+	fs.StringVar(&o.merchants, "merchants", "", "merchant aliases file (JSON)")
+}
+
+// session is everything loaded from the inputs and configuration
+type session struct {
+	statements  []Statement
+	report      LoadReport
+	categorized CategorizedTransactions
+	categorizer *Categorizer
+	categories  CategorySet
+	overrides   *OverrideStore
+	merchants   *MerchantResolver
+}
+
+// loadSession loads and categorizes statements. With useClassifier false
+// only rules and overrides assign categories, which is what training needs.
+func loadSession(o options, inputs []string, useClassifier bool) (*session, error) {
+	s := &session{}
+
+	// Register CSV importers for the saved column mapping profiles
+	profiles, err := LoadCSVProfiles(o.csvProfile)
+	if err != nil {
+		return nil, fmt.Errorf("loading CSV profiles: %w", err)
+	}
+	RegisterCSVProfiles(profiles)
+
+	// Load and merge statements from every input
+	s.statements, s.report, err = LoadStatementFiles(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("loading statements: %w", err)
+	}
+
+	// Restrict to the requested date range
+	dateRange, err := ParseDateRange(o.from, o.to)
+	if err != nil {
+		return nil, fmt.Errorf("in date range: %w", err)
+	}
+	s.statements = FilterByDate(s.statements, dateRange)
+
+	// Categorize transactions with user rules ahead of the built-in ones,
+	// then the classifier for whatever the rules leave in Other
+	userRules, err := LoadUserRules(o.rules)
+	if err != nil {
+		return nil, fmt.Errorf("loading rules: %w", err)
+	}
+	s.categorizer = NewCategorizer(userRules)
+	if useClassifier {
+		s.categorizer.Classifier, err = LoadClassifier(o.classifier)
+		if err != nil {
+			return nil, fmt.Errorf("loading classifier: %w", err)
+		}
+		s.categorizer.MinConfidence = o.minConfidence
+	}
+	CategorizeTransactions(s.statements, s.categorizer)
+
+	// Group descriptions under canonical merchant names
+	aliases, err := LoadMerchantAliases(o.merchants)
+	if err != nil {
+		return nil, fmt.Errorf("loading merchant aliases: %w", err)
+	}
+	s.merchants = NewMerchantResolver(aliases)
+	ResolveMerchants(s.statements, s.merchants)
+
+	// Manual corrections win over every rule
+	s.overrides, err = LoadOverrides(o.overrides)
+	if err != nil {
+		return nil, fmt.Errorf("loading overrides: %w", err)
+	}
+	s.overrides.Apply(s.statements)
+	s.categorized = GroupTransactions(s.statements)
+
+	// Load the category set, adding any category only named by a rule,
+	// an override or the classifier
+	s.categories, err = LoadCategories(o.categories)
+	if err != nil {
+		return nil, fmt.Errorf("loading categories: %w", err)
+	}
+	for _, rule := range s.categorizer.Rules {
+		s.categories.Ensure(rule.Category)
+	}
+	s.categories.Ensure(s.overrides.Categories()...)
+	if s.categorizer.Classifier != nil {
+		s.categories.Ensure(s.categorizer.Classifier.Categories()...)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// groupTags returns the tags of the transactions in the summary groups,
+// keyed by description
+func groupTags(c CategorizedTransactions) map[string][]string {
+	tags := make(map[string][]string)
+	for _, group := range [][]Transaction{c.ApplePay, c.PayPal, c.LinePay, c.Jkopay, c.ForeignFees, c.Other} {
+		for _, tx := range group {
+			tags[tx.Description] = tx.Tags
+		}
+	}
+	return tags
+}
+
+func TestSummaryGroupsFollowEdits(t *testing.T) {
+	statements := journalStatements()
+	statements[1].Transactions = append(statements[1].Transactions, journalTx("APE1234 COFFEE", 90, "2025-03-05", "2025-03-06"))
+	categorizer := NewCategorizer(nil)
+	CategorizeTransactions(statements, categorizer)
+
+	// Overrides saved by an earlier run are applied after categorizing
+	overrides := &OverrideStore{Path: filepath.Join(t.TempDir(), "overrides.json"), Overrides: make(map[string]Override)}
+	overrides.SetTags(statements[1].Transactions[1], []string{"work"})
+	overrides.Apply(statements)
+	categorized := GroupTransactions(statements)
+	if got := groupTags(categorized)["APE1234 COFFEE"]; len(got) != 1 || got[0] != "work" {
+		t.Fatalf("groups built after overrides have tags %q", got)
+	}
+	if len(categorized.ApplePay) != 1 || categorized.ApplePay[0].ApplePayCardLast4 != "1234" {
+		t.Fatalf("Apple Pay group = %+v", categorized.ApplePay)
+	}
+
+	m := initialModel(statements, categorized, DefaultCategories(), categorizer, overrides)
+	breakfast := statements[1].Transactions[0]
+	m = m.overrideCategory(breakfast, CategoryTravel)
+	m = m.annotate(breakfast, func(t *Transaction) { t.Tags = []string{"trip"} }, "")
+
+	for _, tx := range m.categorized.Other {
+		if tx.Description == "BREAKFAST" && (tx.Category != CategoryTravel || len(tx.Tags) != 1 || tx.Tags[0] != "trip") {
+			t.Errorf("summary group still has %s %q after the edits", tx.Category, tx.Tags)
+		}
+	}
+	summary := BuildSummary(m.statements, m.categorized, m.categories)
+	if summary.Other.Count != 3 || summary.ApplePay.Count != 1 {
+		t.Errorf("group counts %d and %d, want 3 and 1", summary.Other.Count, summary.ApplePay.Count)
+	}
+	found := false
+	for _, total := range summary.Categories {
+		found = found || (total.Category == CategoryTravel && total.Count == 1)
+	}
+	if !found {
+		t.Errorf("summary categories %+v miss the overridden Travel purchase", summary.Categories)
+	}
+}
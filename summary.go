@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupTotal counts and totals a group of transactions
+type GroupTotal struct {
+	Count  int    `json:"count"`
+	Totals Totals `json:"totals"`
+}
+
+// CardTotal counts and totals the Apple Pay transactions of one card
+type CardTotal struct {
+	Card   string `json:"card"` // Last 4 digits, "Unknown" when missing
+	Count  int    `json:"count"`
+	Totals Totals `json:"totals"`
+}
+
+// Summary is everything the summary view shows
+type Summary struct {
+	Statements    int             `json:"statements"`
+	ApplePay      GroupTotal      `json:"applePay"`
+	PayPal        GroupTotal      `json:"payPal"`
+	LinePay       GroupTotal      `json:"linePay"`
+	Jkopay        GroupTotal      `json:"jkopay"`
+	ForeignFees   GroupTotal      `json:"foreignFees"`
+	Other         GroupTotal      `json:"other"`
+	ApplePayCards []CardTotal     `json:"applePayCards"` // Sorted by card
+	Categories    []CategoryTotal `json:"categories"`    // Purchases, with parents rolling up their subcategories
+	Tags          []TagTotal      `json:"tags"`
+	BusiestDays   []DayTotal      `json:"busiestDays"` // Five days with the most purchases
+}
+
+// groupTotal counts and totals the billed amounts of transactions
+func groupTotal(txs []Transaction) GroupTotal {
+	return GroupTotal{Count: len(txs), Totals: billedTotals(txs)}
+}
+
+// BuildSummary aggregates the statements for the summary view
+func BuildSummary(statements []Statement, categorized CategorizedTransactions, categories CategorySet) Summary {
+	s := Summary{
+		Statements:  len(statements),
+		ApplePay:    groupTotal(categorized.ApplePay),
+		PayPal:      groupTotal(categorized.PayPal),
+		LinePay:     groupTotal(categorized.LinePay),
+		Jkopay:      groupTotal(categorized.Jkopay),
+		ForeignFees: groupTotal(categorized.ForeignFees),
+		Other:       groupTotal(categorized.Other),
+	}
+
+	// Apple Pay breakdown by card
+	byCard := make(map[string][]Transaction)
+	for _, tx := range categorized.ApplePay {
+		card := tx.ApplePayCardLast4
+		if card == "" {
+			card = "Unknown"
+		}
+		byCard[card] = append(byCard[card], tx)
+	}
+	for card, txs := range byCard {
+		s.ApplePayCards = append(s.ApplePayCards, CardTotal{Card: card, Count: len(txs), Totals: billedTotals(txs)})
+	}
+	sort.Slice(s.ApplePayCards, func(i, j int) bool { return s.ApplePayCards[i].Card < s.ApplePayCards[j].Card })
+
+	var all, purchases []Transaction
+	for _, stmt := range statements {
+		for _, tx := range stmt.Transactions {
+			all = append(all, tx)
+			if tx.BilledAmount.Sign() > 0 {
+				purchases = append(purchases, tx)
+			}
+		}
+	}
+	s.Categories = categories.RollUp(purchases)
+	s.Tags = TagTotals(all)
+
+	// Busiest days by number of purchases
+	s.BusiestDays = DailyTotals(purchases)
+	sort.SliceStable(s.BusiestDays, func(i, j int) bool { return s.BusiestDays[i].Count > s.BusiestDays[j].Count })
+	if len(s.BusiestDays) > 5 {
+		s.BusiestDays = s.BusiestDays[:5]
+	}
+
+	return s
+}
+
+// Write prints the summary as text
+func (s Summary) Write(w io.Writer, categories CategorySet) {
+	fmt.Fprintf(w, "Total Statements: %d\n", s.Statements)
+	groups := []struct {
+		name  string
+		total GroupTotal
+	}{
+		{"Apple Pay", s.ApplePay},
+		{"PayPal", s.PayPal},
+		{"LINE Pay", s.LinePay},
+		{"Jkopay", s.Jkopay},
+		{"Foreign Fees", s.ForeignFees},
+		{"Other", s.Other},
+	}
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s: %d transactions, Total: %s\n", g.name, g.total.Count, g.total.Totals)
+	}
+
+	fmt.Fprintln(w, "\nApple Pay by Card")
+	for _, card := range s.ApplePayCards {
+		fmt.Fprintf(w, "  Card ending in %s: %d transactions, Total: %s\n", card.Card, card.Count, card.Totals)
+	}
+
+	fmt.Fprintln(w, "\nSpending by Category")
+	for _, total := range s.Categories {
+		indent := strings.Repeat("  ", CategoryDepth(total.Category)+1)
+		fmt.Fprintf(w, "%s%s: %d transactions, Total: %s\n", indent, categories.Label(total.Category), total.Count, total.Totals)
+	}
+
+	if len(s.Tags) > 0 {
+		fmt.Fprintln(w, "\nTags")
+		for _, total := range s.Tags {
+			fmt.Fprintf(w, "  #%s: %d transactions, Total: %s\n", total.Tag, total.Count, total.Totals)
+		}
+	}
+
+	fmt.Fprintln(w, "\nBusiest Days")
+	for _, day := range s.BusiestDays {
+		fmt.Fprintf(w, "  %s: %d transactions, Total: %s\n", day.Date.Format(displayDateLayout), day.Count, day.Totals)
+	}
+}
+
+// StatementInfo is the header of a statement with its parsed amounts
+type StatementInfo struct {
+	Period          string    `json:"statement"` // Year and month, e.g. 2025/03
+	Date            time.Time `json:"statementDate"`
+	PaymentDue      time.Time `json:"paymentDue"`
+	Currency        string    `json:"currency"`
+	Total           Money     `json:"total"`
+	MinPayment      Money     `json:"minPayment"`
+	CreditLimit     Money     `json:"creditLimit"`
+	PreviousBalance Money     `json:"previousBalance"`
+	Transactions    int       `json:"transactions"`
+	Source          string    `json:"source"`
+}
+
+// NewStatementInfo describes a statement
+func NewStatementInfo(stmt Statement) StatementInfo {
+	return StatementInfo{
+		Period:          fmt.Sprintf("%s/%s", stmt.StmtYr, stmt.StmtMo),
+		Date:            stmt.StatementDate,
+		PaymentDue:      stmt.PaymentDue,
+		Currency:        stmt.Currency,
+		Total:           stmt.Total,
+		MinPayment:      stmt.MinPayment,
+		CreditLimit:     stmt.CreditLimit,
+		PreviousBalance: stmt.PreviousBalance,
+		Transactions:    len(stmt.Transactions),
+		Source:          stmt.Source,
+	}
+}
@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 // Transaction represents a single credit card transaction
 type Transaction struct {
 	Amount            string `json:"amount"`
@@ -15,10 +17,23 @@ type Transaction struct {
 	CardNo            string `json:"cardNo"`
 	RelationShip      string `json:"relationShip"`
 
+	// Parsed amounts, filled in at load time
+	OriginalAmount Money // Amount in AmtCy, or the statement currency when blank
+	BilledAmount   Money // NtdAmount, in the statement currency
+
+	// Parsed dates, filled in at load time; zero when missing or invalid
+	TransactionDate time.Time // TxnDate
+	PostedDate      time.Time // PostingDate
+	ConversionDate  time.Time // CyCnvDate
+
 	// Added fields for categorization
 	NormalizedDescription string
 	ApplePayCardLast4     string
+	Merchant              string // Canonical merchant name, used for grouping
 	Category              string
+	Confidence            float64 // Classifier confidence when Category was suggested rather than matched by a rule
+	Tags                  []string
+	Note                  string
 }
 
 // Statement represents a monthly credit card statement
@@ -46,6 +61,26 @@ type Statement struct {
 	PointCurUsePt   string        `json:"pointCurUsePt"`
 	Message         string        `json:"message"`
 	Transactions    []Transaction `json:"transactions"`
+
+	// Source is the file the statement was loaded from
+	Source string `json:"-"`
+	// Currency is the ISO 4217 billing currency, TWD for HSBC Taiwan
+	Currency string `json:"-"`
+	// Issues are problems the parser found in the file, reported with the
+	// load issues
+	Issues []string `json:"-"`
+
+	// Parsed amounts, filled in at load time
+	Total            Money `json:"-"` // CurTotAmt
+	MinPayment       Money `json:"-"` // MinAmt
+	CreditLimit      Money `json:"-"` // CreditLmt
+	CashAdvanceLimit Money `json:"-"` // CashAdvLmt
+	PreviousBalance  Money `json:"-"` // PreBal
+
+	// Parsed dates, filled in at load time; zero when missing or invalid
+	Period        time.Time `json:"-"` // First day of StmtYr/StmtMo
+	StatementDate time.Time `json:"-"` // StmtDate
+	PaymentDue    time.Time `json:"-"` // PmtDue
 }
 
 // CategorizedTransactions holds transactions grouped by category
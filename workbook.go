@@ -0,0 +1,134 @@
+package main
+
+import "strings"
+
+// workbookColumns are the transaction columns of the statement sheets
+var workbookColumns = []string{
+	"Date", "Posted", "Category", "Billed", "Currency", "Original", "Original Currency",
+	"Description", "Location", "Card", "Apple Pay Card", "Merchant", "Tags", "Note",
+}
+
+// workbookWidths are the widths of workbookColumns
+var workbookWidths = []float64{11, 11, 22, 12, 9, 12, 9, 36, 16, 8, 8, 20, 16, 24}
+
+// BuildWorkbook lays out the statements as worksheets: the summary, one
+// sheet per statement with its header fields above its transactions, and
+// every transaction in one flat table for pivoting
+func BuildWorkbook(statements []Statement, summary Summary, categories CategorySet) []xlsxSheet {
+	sheets := []xlsxSheet{summarySheet(summary, categories)}
+	flat := xlsxSheet{
+		name:   "Transactions",
+		rows:   [][]xlsxCell{xlsxHeadings(append([]string{"Statement"}, append(workbookColumns, "Foreign")...))},
+		widths: append([]float64{9}, append(workbookWidths, 8)...),
+	}
+
+	for i, stmt := range statements {
+		info := NewStatementInfo(stmt)
+		sheet := xlsxSheet{
+			name: strings.ReplaceAll(info.Period, "/", "-"),
+			rows: [][]xlsxCell{
+				{xlsxHeading("Statement"), xlsxText(info.Period)},
+				{xlsxHeading("Statement Date"), xlsxDateCell(stmt.StatementDate)},
+				{xlsxHeading("Payment Due"), xlsxDateCell(stmt.PaymentDue)},
+				{xlsxHeading("Total"), xlsxMoney(stmt.Total)},
+				{xlsxHeading("Minimum Payment"), xlsxMoney(stmt.MinPayment)},
+				{xlsxHeading("Credit Limit"), xlsxMoney(stmt.CreditLimit)},
+				{xlsxHeading("Previous Balance"), xlsxMoney(stmt.PreviousBalance)},
+				{xlsxHeading("Currency"), xlsxText(stmt.Currency)},
+				{xlsxHeading("Source"), xlsxText(stmt.Source)},
+				{},
+				xlsxHeadings(workbookColumns),
+			},
+			widths: append([]float64{}, workbookWidths...),
+		}
+		sheet.widths[0] = max(sheet.widths[0], 17)
+
+		for _, tx := range stmt.Transactions {
+			row := newTransactionRow(statements, i, tx)
+			cells := transactionCells(row)
+			sheet.rows = append(sheet.rows, cells)
+			flat.rows = append(flat.rows, append(append([]xlsxCell{xlsxText(row.Period)}, cells...), xlsxBool(row.Foreign)))
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	return append(sheets, flat)
+}
+
+// transactionCells fills the workbookColumns of one transaction
+func transactionCells(row TransactionRow) []xlsxCell {
+	date := xlsxDateCell(row.Date)
+	if row.Date.IsZero() {
+		date = xlsxText(row.RawDate)
+	}
+	return []xlsxCell{
+		date,
+		xlsxDateCell(row.Transaction.PostedDate),
+		xlsxText(row.Category),
+		xlsxMoney(row.Billed),
+		xlsxText(row.Billed.Currency),
+		xlsxMoney(row.Original),
+		xlsxText(row.Original.Currency),
+		xlsxText(row.Description),
+		xlsxText(row.Location),
+		xlsxText(row.Card),
+		xlsxText(row.ApplePayCard),
+		xlsxText(row.Merchant),
+		xlsxText(strings.Join(row.Tags, " ")),
+		xlsxText(row.Note),
+	}
+}
+
+// summarySheet lays out the totals of the summary view, one row per
+// currency of each total
+func summarySheet(s Summary, categories CategorySet) xlsxSheet {
+	sheet := xlsxSheet{
+		name:   "Summary",
+		rows:   [][]xlsxCell{{xlsxHeading("Statements"), xlsxInt(s.Statements)}},
+		widths: []float64{28, 13, 14, 9},
+	}
+	section := func(title string) {
+		sheet.rows = append(sheet.rows, []xlsxCell{}, xlsxHeadings([]string{title, "Transactions", "Amount", "Currency"}))
+	}
+	add := func(name string, count int, totals Totals) {
+		if len(totals) == 0 {
+			sheet.rows = append(sheet.rows, []xlsxCell{xlsxText(name), xlsxInt(count)})
+		}
+		for _, c := range totals.Currencies() {
+			sheet.rows = append(sheet.rows, []xlsxCell{xlsxText(name), xlsxInt(count), xlsxMoney(totals[c]), xlsxText(c)})
+		}
+	}
+
+	section("Group")
+	add("Apple Pay", s.ApplePay.Count, s.ApplePay.Totals)
+	add("PayPal", s.PayPal.Count, s.PayPal.Totals)
+	add("LINE Pay", s.LinePay.Count, s.LinePay.Totals)
+	add("Jkopay", s.Jkopay.Count, s.Jkopay.Totals)
+	add("Foreign Fees", s.ForeignFees.Count, s.ForeignFees.Totals)
+	add("Other", s.Other.Count, s.Other.Totals)
+
+	if len(s.ApplePayCards) > 0 {
+		section("Apple Pay Card")
+		for _, card := range s.ApplePayCards {
+			add(card.Card, card.Count, card.Totals)
+		}
+	}
+
+	section("Category")
+	for _, total := range s.Categories {
+		add(strings.Repeat("  ", CategoryDepth(total.Category))+categories.Label(total.Category), total.Count, total.Totals)
+	}
+
+	if len(s.Tags) > 0 {
+		section("Tag")
+		for _, total := range s.Tags {
+			add("#"+total.Tag, total.Count, total.Totals)
+		}
+	}
+
+	section("Busiest Day")
+	for _, day := range s.BusiestDays {
+		add(day.Date.Format("2006-01-02"), day.Count, day.Totals)
+	}
+	return sheet
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxStyle indexes the cell formats of the styles part
+type xlsxStyle int
+
+const (
+	xlsxPlain  xlsxStyle = iota
+	xlsxBold             // Headings
+	xlsxDate             // yyyy-mm-dd
+	xlsxAmount           // #,##0.00
+)
+
+// xlsxCell is one cell of a worksheet: a number when value is set, else
+// text, and empty when both are
+type xlsxCell struct {
+	text    string
+	value   string // Number as a decimal literal, or 0 or 1 for a boolean
+	boolean bool
+	style   xlsxStyle
+}
+
+// xlsxSheet is a named worksheet of rows of cells
+type xlsxSheet struct {
+	name   string
+	rows   [][]xlsxCell
+	widths []float64 // Column widths in characters, default when zero
+}
+
+// xlsxText is a text cell
+func xlsxText(s string) xlsxCell {
+	return xlsxCell{text: s}
+}
+
+// xlsxHeading is a bold text cell
+func xlsxHeading(s string) xlsxCell {
+	return xlsxCell{text: s, style: xlsxBold}
+}
+
+// xlsxHeadings is a row of bold text cells
+func xlsxHeadings(names []string) []xlsxCell {
+	cells := make([]xlsxCell, len(names))
+	for i, name := range names {
+		cells[i] = xlsxHeading(name)
+	}
+	return cells
+}
+
+// xlsxMoney is a number cell with the exact amount
+func xlsxMoney(m Money) xlsxCell {
+	return xlsxCell{value: m.String(), style: xlsxAmount}
+}
+
+// xlsxInt is a number cell
+func xlsxInt(n int) xlsxCell {
+	return xlsxCell{value: strconv.Itoa(n)}
+}
+
+// xlsxBool is a TRUE or FALSE cell
+func xlsxBool(b bool) xlsxCell {
+	if b {
+		return xlsxCell{value: "1", boolean: true}
+	}
+	return xlsxCell{value: "0", boolean: true}
+}
+
+// xlsxDateCell is a date cell, or an empty cell for a zero time
+func xlsxDateCell(t time.Time) xlsxCell {
+	if t.IsZero() {
+		return xlsxCell{}
+	}
+	// Excel counts days from 1899-12-30
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(day.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	return xlsxCell{value: strconv.Itoa(days), style: xlsxDate}
+}
+
+// xlsxColumn returns the letters of a zero-based column index, e.g. 27 is AB
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxSheetName makes a worksheet name valid and unique: at most 31
+// characters, none of []:*?/\, and not used by an earlier sheet
+func xlsxSheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "Sheet"
+	}
+	base := []rune(name)
+	for n := 1; ; n++ {
+		candidate := string(base[:min(len(base), 31)])
+		if n > 1 {
+			suffix := fmt.Sprintf(" (%d)", n)
+			candidate = string(base[:min(len(base), 31-len(suffix))]) + suffix
+		}
+		if !used[strings.ToLower(candidate)] {
+			used[strings.ToLower(candidate)] = true
+			return candidate
+		}
+	}
+}
+
+// xlsxEscape escapes text for XML content and attributes
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// WriteXLSX writes worksheets as an Office Open XML workbook
+func WriteXLSX(w io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	var types, workbook, rels strings.Builder
+	types.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+
+	used := make(map[string]bool)
+	worksheets := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(xlsxSheetName(sheet.name, used)), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		worksheets[i] = xlsxWorksheet(sheet)
+	}
+	types.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", types.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, content := range worksheets {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), content})
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxWorksheet renders the XML of one worksheet
+func xlsxWorksheet(sheet xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sheet.widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range sheet.widths {
+			if width > 0 {
+				fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+			}
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for r, row := range sheet.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", xlsxColumn(c), r+1)
+			switch {
+			case cell.boolean:
+				fmt.Fprintf(&b, `<c r="%s" t="b" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			case cell.value != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			case cell.text != "":
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, xlsxEscape(cell.text))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxStyles defines the cell formats in the order of xlsxStyle
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
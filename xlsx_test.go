@@ -0,0 +1,98 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteXLSXSheets(t *testing.T) {
+	t.Setenv("STATEMENTS_CONFIG_DIR", t.TempDir())
+	s, err := loadSession(options{}, []string{filepath.Join("testdata", "hsbc", "2025-03.json")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second statement of the same month needs its own sheet name
+	statements := append(s.statements, s.statements[0])
+	summary := BuildSummary(statements, s.categorized, s.categories)
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, BuildWorkbook(statements, summary, s.categories)); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		parts[f.Name] = string(data)
+
+		// Every part must be well-formed XML
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal([]byte(parts["xl/workbook.xml"]), &workbook); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i, sheet := range workbook.Sheets {
+		names = append(names, sheet.Name)
+		if _, ok := parts[fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)]; !ok {
+			t.Errorf("sheet %q has no worksheet part", sheet.Name)
+		}
+	}
+	if got := strings.Join(names, "|"); got != "Summary|2025-03|2025-03 (2)|Transactions" {
+		t.Errorf("sheets = %s", got)
+	}
+	if _, ok := parts[fmt.Sprintf("xl/worksheets/sheet%d.xml", len(names)+1)]; ok {
+		t.Errorf("workbook has more worksheet parts than its %d sheets", len(names))
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet4.xml"], "UNIQLO TAIPEI 101") {
+		t.Errorf("Transactions sheet lacks the transactions")
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	used := make(map[string]bool)
+	tests := []struct {
+		name, want string
+	}{
+		{"Summary", "Summary"},
+		{"summary", "summary (2)"},
+		{"2025/03", "2025-03"},
+		{`a[b]c:d*e?f\g`, "a-b-c-d-e-f-g"},
+		{"", "Sheet"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 31)},
+		{strings.Repeat("x", 40), strings.Repeat("x", 27) + " (2)"},
+		{"月結單", "月結單"},
+	}
+	for _, tt := range tests {
+		if got := xlsxSheetName(tt.name, used); got != tt.want {
+			t.Errorf("xlsxSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}